@@ -0,0 +1,164 @@
+//go:build !nogui
+
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// отступ окна с отчетом от края игровой зоны
+const analysis_dialog_margin = 20
+
+// клавиши анализа открывают окно с отчетом, любая из них или Esc его закрывает
+func (g *MyGame) analysisKeyEvent() {
+	g.receive_emitter()
+	g.receive_oscillator()
+	g.receive_predecessor()
+	var emitter = g.is_action_just_pressed(action_analyze_emitter)
+	var oscillator = g.is_action_just_pressed(action_measure_osc)
+	var predecessor = g.is_action_just_pressed(action_predecessor)
+	if g.analysis_lines != nil {
+		if emitter || oscillator || predecessor || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			g.analysis_lines = nil
+		}
+		return
+	}
+
+	if emitter && !g.emitter_busy {
+		// до emitter_max_gens поколений, для паффера это десятки секунд
+		var p = pattern_from_cells(g.analysis_cells())
+		g.emitter_busy = true
+		g.analysis_lines = []string{"Emitter analysis:", "running..."}
+		go func() {
+			g.emitter_done <- analyze_emitter(p, emitter_max_gens)
+		}()
+	}
+	if oscillator && !g.oscillator_busy {
+		var cells = g.analysis_cells()
+		g.oscillator_busy = true
+		g.analysis_lines = []string{"Oscillator metrics:", "running..."}
+		go func() {
+			if m, err := measure_oscillator(cells); err != nil {
+				g.oscillator_done <- []string{err.Error()}
+			} else {
+				g.oscillator_done <- m.lines()
+			}
+		}()
+	}
+	if predecessor && !g.predecessor_busy {
+		g.start_predecessor()
+	}
+	if g.is_action_just_pressed(action_export_metrics) {
+		var cells = g.analysis_cells()
+		go func() {
+			if err := export_metrics(metrics_file, cells); err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+}
+
+// забираем итог анализа излучателя, если он закончился
+func (g *MyGame) receive_emitter() {
+	select {
+	case report := <-g.emitter_done:
+		g.emitter_busy = false
+		g.analysis_lines = append([]string{"Emitter analysis:"}, report.lines()...)
+	default:
+	}
+}
+
+// забираем метрики осциллятора, если они посчитаны
+func (g *MyGame) receive_oscillator() {
+	select {
+	case lines := <-g.oscillator_done:
+		g.oscillator_busy = false
+		g.analysis_lines = append([]string{"Oscillator metrics:"}, lines...)
+	default:
+	}
+}
+
+// ищем предшественника шаблона в руке или выделения вместе с пустыми клетками в нем;
+// все поле не берем: на настоящем поле задача выполнимости не решится за predecessor_timeout
+func (g *MyGame) start_predecessor() {
+	var target pattern
+	switch {
+	case g.is_figure_draw:
+		target = pattern_from_cells(g.analysis_cells())
+	case !g.selection_box().empty:
+		target = pattern_from_field(g.field, g.selection_box())
+	default:
+		g.analysis_lines = []string{"Predecessor search:", "select a region with the Select tool or take a pattern in hand"}
+		return
+	}
+	// правило берем сейчас, горутина глобальные настройки не читает
+	var rule = active_rule
+	g.predecessor_busy = true
+	g.analysis_lines = []string{"Predecessor search:", "searching..."}
+	go func() {
+		g.predecessor_done <- find_predecessors(target, predecessor_margin, predecessor_count, predecessor_timeout, rule)
+	}()
+}
+
+// забираем итог поиска предшественника, если он закончился; найденный шаблон даем в руку
+func (g *MyGame) receive_predecessor() {
+	select {
+	case result := <-g.predecessor_done:
+		g.predecessor_busy = false
+		g.analysis_lines = append([]string{"Predecessor search:"}, result.lines(predecessor_margin)...)
+		if len(result.predecessors) > 0 {
+			g.pixels = pattern_from_cells(result.predecessors[0]).to_pixels()
+			g.is_figure_draw = true
+			g.analysis_lines = append(g.analysis_lines, "The predecessor is in hand, click to place it")
+		}
+	default:
+	}
+}
+
+// метрики считаются до metrics_max_period поколений, поэтому вызывается из горутины
+func export_metrics(filename string, cells []POS) error {
+	m, err := measure_oscillator(cells)
+	if err != nil {
+		return err
+	}
+	if err := save_metrics(filename, m); err != nil {
+		return err
+	}
+	log.Printf("oscillator metrics saved to %s", filename)
+	return nil
+}
+
+// анализируем шаблон в руке, например ружье Госпера с панели, иначе выделение, а если нет и его - все поле
+func (g *MyGame) analysis_cells() []POS {
+	if !g.is_figure_draw {
+		if !g.selection_box().empty {
+			return g.selection_cells()
+		}
+		return g.board_universe().cells()
+	}
+	var cells = []POS{}
+	for _, pixel := range g.pixels {
+		if pixel.value == 1 {
+			cells = append(cells, POS{pixel.x, pixel.y})
+		}
+	}
+	return cells
+}
+
+func (g *MyGame) drawAnalysis(screen *ebiten.Image) {
+	var lines = slices.Concat(g.analysis_lines, []string{"", "Esc: close"})
+	var width = 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+	// 6 пикселей на символ отладочного шрифта
+	ebitenutil.DrawRect(screen, analysis_dialog_margin-4, analysis_dialog_margin-4,
+		float64(width*6+8), float64(len(lines)*hud_line_height+8), white)
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), analysis_dialog_margin, analysis_dialog_margin)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// больше кадров не пишем, чтобы не съесть всю память
+	max_animation_frames = 5000
+	// задержка кадра в APNG - 16 бит, в миллисекундах больше не запишется
+	max_animation_delay = 65535
+)
+
+// что записывать в анимацию и как рисовать кадры
+type animation_settings struct {
+	from  int // первое поколение
+	to    int // последнее поколение, включительно
+	cell  int // размер клетки в пикселях
+	delay int // задержка кадра в миллисекундах
+	theme theme
+	grid  bool
+	// область в абсолютных координатах, пустая - все клетки всех кадров
+	crop bounding_box
+}
+
+func (s animation_settings) validate() error {
+	if s.from < 0 || s.to < s.from {
+		return fmt.Errorf("generations %d..%d: expected 0 <= from <= to", s.from, s.to)
+	}
+	if s.to-s.from+1 > max_animation_frames {
+		return fmt.Errorf("generations %d..%d: at most %d frames", s.from, s.to, max_animation_frames)
+	}
+	if s.cell < 1 || s.cell > 64 {
+		return fmt.Errorf("cell size %d: expected 1..64", s.cell)
+	}
+	if s.delay < 10 || s.delay > max_animation_delay {
+		return fmt.Errorf("frame delay %d ms: expected 10..%d", s.delay, max_animation_delay)
+	}
+	return nil
+}
+
+// разбираем область "x,y,w,h"
+func parse_crop(text string) (bounding_box, error) {
+	var parts = strings.Split(text, ",")
+	if len(parts) != 4 {
+		return bounding_box{}, fmt.Errorf("crop %q: expected x,y,w,h", text)
+	}
+	var values = [4]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return bounding_box{}, fmt.Errorf("crop %q: expected x,y,w,h", text)
+		}
+		values[i] = n
+	}
+	if values[2] < 1 || values[3] < 1 {
+		return bounding_box{}, fmt.Errorf("crop %q: width and height must be positive", text)
+	}
+	return bounding_box{values[0], values[1], values[0] + values[2] - 1, values[1] + values[3] - 1, false}, nil
+}
+
+// считаем поколения from..to и рисуем каждое в отдельный кадр
+func record_animation(u *universe, s animation_settings) []*image.Paletted {
+	for u.generation < s.from {
+		u.step()
+	}
+	var frames = [][]POS{}
+	for {
+		frames = append(frames, u.cells())
+		if u.generation >= s.to {
+			break
+		}
+		u.step()
+	}
+
+	// если область не задана, берем прямоугольник вокруг клеток всех кадров
+	var crop = s.crop
+	if crop.empty {
+		for _, cells := range frames {
+			for _, c := range cells {
+				if crop.empty {
+					crop = bounding_box{c.x, c.y, c.x, c.y, false}
+				}
+				crop.min_x = min(crop.min_x, c.x)
+				crop.min_y = min(crop.min_y, c.y)
+				crop.max_x = max(crop.max_x, c.x)
+				crop.max_y = max(crop.max_y, c.y)
+			}
+		}
+		if crop.empty {
+			crop = bounding_box{0, 0, 0, 0, false}
+		}
+	}
+
+	var images = []*image.Paletted{}
+	for _, cells := range frames {
+		images = append(images, render_cells(cells, crop, s.cell, s.theme, s.grid))
+	}
+	return images
+}
+
+// анимированный GIF, задержка в GIF хранится в сотых долях секунды
+func write_gif(w io.Writer, frames []*image.Paletted, delay int) error {
+	var anim = &gif.GIF{}
+	for _, frame := range frames {
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay/10)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// кусок PNG: длина, тип, данные и CRC
+type png_chunk struct {
+	kind string
+	data []byte
+}
+
+// разбираем PNG, который написал image/png, на куски
+func read_png_chunks(data []byte) ([]png_chunk, error) {
+	var chunks = []png_chunk{}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("png: too short")
+	}
+	data = data[8:]
+	for len(data) >= 12 {
+		var length = int(binary.BigEndian.Uint32(data[0:4]))
+		if len(data) < 12+length {
+			return nil, fmt.Errorf("png: truncated chunk")
+		}
+		chunks = append(chunks, png_chunk{string(data[4:8]), data[8 : 8+length]})
+		data = data[12+length:]
+	}
+	return chunks, nil
+}
+
+func write_png_chunk(w io.Writer, kind string, data []byte) error {
+	var header = make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], kind)
+	var crc = crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+	var footer = binary.BigEndian.AppendUint32(nil, crc.Sum32())
+	for _, part := range [][]byte{header, data, footer} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// APNG: каждый кадр сжимаем через image/png и переносим его IDAT в fdAT,
+// все кадры одного размера и с одной палитрой
+func write_apng(w io.Writer, frames []*image.Paletted, delay int) error {
+	var encoded = [][]png_chunk{}
+	for _, frame := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			return err
+		}
+		chunks, err := read_png_chunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, chunks)
+	}
+
+	if _, err := w.Write([]byte("\x89PNG\r\n\x1a\n")); err != nil {
+		return err
+	}
+	var sequence = uint32(0)
+	var frame_control = func() []byte {
+		var data = make([]byte, 26)
+		binary.BigEndian.PutUint32(data[0:4], sequence)
+		binary.BigEndian.PutUint32(data[4:8], uint32(frames[0].Rect.Dx()))
+		binary.BigEndian.PutUint32(data[8:12], uint32(frames[0].Rect.Dy()))
+		binary.BigEndian.PutUint16(data[20:22], uint16(delay))
+		binary.BigEndian.PutUint16(data[22:24], 1000)
+		sequence++
+		return data
+	}
+
+	// заголовок и палитру берем из первого кадра, после IHDR пишем acTL
+	for _, chunk := range encoded[0] {
+		switch chunk.kind {
+		case "IHDR":
+			if err := write_png_chunk(w, chunk.kind, chunk.data); err != nil {
+				return err
+			}
+			var control = make([]byte, 8)
+			binary.BigEndian.PutUint32(control[0:4], uint32(len(frames)))
+			if err := write_png_chunk(w, "acTL", control); err != nil {
+				return err
+			}
+		case "PLTE", "tRNS":
+			if err := write_png_chunk(w, chunk.kind, chunk.data); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, chunks := range encoded {
+		if err := write_png_chunk(w, "fcTL", frame_control()); err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			if chunk.kind != "IDAT" {
+				continue
+			}
+			// первый кадр остается обычной картинкой для программ без поддержки APNG
+			if i == 0 {
+				if err := write_png_chunk(w, "IDAT", chunk.data); err != nil {
+					return err
+				}
+				continue
+			}
+			var data = binary.BigEndian.AppendUint32(nil, sequence)
+			sequence++
+			if err := write_png_chunk(w, "fdAT", append(data, chunk.data...)); err != nil {
+				return err
+			}
+		}
+	}
+	return write_png_chunk(w, "IEND", nil)
+}
+
+// пишем анимацию в файл, формат определяем по расширению
+func save_animation(filename string, frames []*image.Paletted, delay int) error {
+	var write func(io.Writer, []*image.Paletted, int) error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gif":
+		write = write_gif
+	case ".png", ".apng":
+		write = write_apng
+	default:
+		return fmt.Errorf("%s: expected .gif, .png or .apng", filename)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(file, frames, delay); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", filename, err)
+	}
+	return file.Close()
+}
+
+// life animate: записываем поколения from..to в GIF или APNG
+func animate_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life animate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var settings = animation_settings{}
+	var theme_name, crop string
+	var output = flags.String("o", "life.gif", "output file, .gif or .png/.apng")
+	flags.IntVar(&settings.from, "from", 0, "first generation")
+	flags.IntVar(&settings.to, "to", 100, "last generation")
+	flags.IntVar(&settings.cell, "cell", 4, "cell size in pixels")
+	flags.IntVar(&settings.delay, "delay", 100, "frame delay in milliseconds")
+	flags.StringVar(&theme_name, "theme", "light", strings.Join(theme_names(), ", "))
+	flags.BoolVar(&settings.grid, "grid", false, "draw grid lines")
+	flags.StringVar(&crop, "crop", "", "region x,y,w,h in pattern coordinates (default: all live cells)")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+
+	var ok bool
+	if settings.theme, ok = themes[theme_name]; !ok {
+		fmt.Fprintf(stderr, "life animate: theme %q: expected one of %s\n", theme_name, strings.Join(theme_names(), ", "))
+		return exit_usage
+	}
+	settings.crop = bounding_box{empty: true}
+	if crop != "" {
+		var err error
+		if settings.crop, err = parse_crop(crop); err != nil {
+			fmt.Fprintln(stderr, "life animate:", err)
+			return exit_usage
+		}
+	}
+	if err := settings.validate(); err != nil {
+		fmt.Fprintln(stderr, "life animate:", err)
+		return exit_usage
+	}
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life animate:", err)
+		return exit_usage
+	}
+
+	var frames = record_animation(new_universe(start), settings)
+	if err := save_animation(*output, frames, settings.delay); err != nil {
+		fmt.Fprintln(stderr, "life animate:", err)
+		return exit_error
+	}
+	fmt.Fprintf(stdout, "%d frames saved to %s\n", len(frames), *output)
+	return exit_ok
+}
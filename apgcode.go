@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// символы расширенного формата Вехслера: 0-9 и a-v - столбец из пяти клеток полосы
+const wechsler_digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// пишем пустые столбцы: 0, w = 2, x = 3, y0..yz = от 4 до 39
+func wechsler_zeros(b *strings.Builder, n int) {
+	for n > 39 {
+		b.WriteString("yz")
+		n -= 39
+	}
+	switch {
+	case n == 1:
+		b.WriteByte('0')
+	case n == 2:
+		b.WriteByte('w')
+	case n == 3:
+		b.WriteByte('x')
+	case n >= 4:
+		b.WriteByte('y')
+		b.WriteByte(wechsler_digits[n-4])
+	}
+}
+
+// расширенный формат Вехслера: поле режем на полосы по 5 строк, каждый столбец полосы -
+// один символ, бит i - клетка в строке i полосы; полосы разделяем z, пустой хвост полосы не пишем
+func wechsler(cells []POS) string {
+	cells = normalize_cells(cells)
+	if len(cells) == 0 {
+		return ""
+	}
+	var size_x, size_y = 0, 0
+	for _, c := range cells {
+		size_x = max(size_x, c.x+1)
+		size_y = max(size_y, c.y+1)
+	}
+	var strips = (size_y + 4) / 5
+	var columns = make([][]int, strips)
+	for i := range columns {
+		columns[i] = make([]int, size_x)
+	}
+	for _, c := range cells {
+		columns[c.y/5][c.x] |= 1 << (c.y % 5)
+	}
+
+	var b strings.Builder
+	for i, strip := range columns {
+		if i > 0 {
+			b.WriteByte('z')
+		}
+		var zeros = 0
+		for _, value := range strip {
+			if value == 0 {
+				zeros++
+				continue
+			}
+			wechsler_zeros(&b, zeros)
+			zeros = 0
+			b.WriteByte(wechsler_digits[value])
+		}
+	}
+	return b.String()
+}
+
+// apgcode как в Catagolue: xs<клеток>_ для натюрмортов, xp<период>_ для осцилляторов,
+// xq<период>_ для кораблей; из всех фаз, поворотов и отражений берем самую короткую
+// запись, при равной длине - первую по алфавиту. Для нестабильных и умерших объектов - ""
+func apgcode(phases [][]POS, result period_result) string {
+	var prefix string
+	switch result.kind {
+	case period_still:
+		prefix = "xs" + strconv.Itoa(len(phases[0]))
+	case period_oscillator:
+		prefix = "xp" + strconv.Itoa(result.period)
+	case period_spaceship:
+		prefix = "xq" + strconv.Itoa(result.period)
+	default:
+		return ""
+	}
+
+	var best = ""
+	for _, phase := range phases {
+		for _, transform := range object_transforms {
+			var moved = make([]POS, len(phase))
+			for i, c := range phase {
+				moved[i] = transform(c)
+			}
+			var code = wechsler(moved)
+			if best == "" || len(code) < len(best) || (len(code) == len(best) && code < best) {
+				best = code
+			}
+		}
+	}
+	return prefix + "_" + best
+}
+
+// apgcode произвольного куска поля: считаем его отдельно, как объект переписи
+func pattern_apgcode(cells []POS) string {
+	var phases, result = object_phases(cells)
+	return apgcode(phases, result)
+}
+
+// похоже ли на apgcode, который мы умеем читать
+func is_apgcode(text string) bool {
+	var prefix, _, ok = strings.Cut(text, "_")
+	if !ok || len(prefix) < 3 {
+		return false
+	}
+	if !strings.HasPrefix(prefix, "xs") && !strings.HasPrefix(prefix, "xp") && !strings.HasPrefix(prefix, "xq") {
+		return false
+	}
+	_, err := strconv.Atoi(prefix[2:])
+	return err == nil
+}
+
+// читаем xs/xp/xq код обратно в шаблон
+func parse_apgcode(code string) (pattern, error) {
+	var p = pattern{}
+	if !is_apgcode(code) {
+		return p, fmt.Errorf("apgcode %q: expected xs, xp or xq code", code)
+	}
+	var _, body, _ = strings.Cut(code, "_")
+	for i, strip := range strings.Split(body, "z") {
+		var x = 0
+		for j := 0; j < len(strip); j++ {
+			var c = strip[j]
+			switch {
+			case c == 'w':
+				x += 2
+			case c == 'x':
+				x += 3
+			case c == 'y':
+				j++
+				if j == len(strip) {
+					return p, fmt.Errorf("apgcode %q: y at the end of a strip", code)
+				}
+				var n = strings.IndexByte(wechsler_digits, strip[j])
+				if n == -1 {
+					return p, fmt.Errorf("apgcode %q: bad character %q after y", code, strip[j])
+				}
+				x += 4 + n
+			default:
+				var value = strings.IndexByte(wechsler_digits[:32], c)
+				if value == -1 {
+					return p, fmt.Errorf("apgcode %q: bad character %q", code, c)
+				}
+				for bit := 0; bit < 5; bit++ {
+					if value&(1<<bit) != 0 {
+						p.cells = append(p.cells, POS{x, i*5 + bit})
+						p.size_y = max(p.size_y, i*5+bit+1)
+					}
+				}
+				x++
+				p.size_x = max(p.size_x, x)
+			}
+		}
+	}
+	if len(p.cells) == 0 {
+		return p, fmt.Errorf("apgcode %q: no cells", code)
+	}
+	return p, nil
+}
+
+// life apgcode: apgcode шаблона из файла или шаблон в RLE по apgcode
+func apgcode_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life apgcode", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var pattern_file = flags.String("pattern", "", "RLE, .cells or PNG pattern to encode")
+	var rule = flags.String("rule", "B3/S23", "rule in B/S notation")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: life apgcode -pattern file | life apgcode code")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	var err error
+	if active_rule, err = parse_rule(*rule); err != nil {
+		fmt.Fprintln(stderr, "life apgcode:", err)
+		return exit_usage
+	}
+
+	if *pattern_file != "" {
+		p, err := load_pattern(*pattern_file)
+		if err != nil {
+			fmt.Fprintln(stderr, "life apgcode:", err)
+			return exit_error
+		}
+		var code = pattern_apgcode(p.cells)
+		if code == "" {
+			fmt.Fprintln(stderr, "life apgcode: pattern does not stabilize within", max_object_period, "generations")
+			return exit_error
+		}
+		fmt.Fprintln(stdout, code)
+		return exit_ok
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exit_usage
+	}
+	p, err := parse_apgcode(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "life apgcode:", err)
+		return exit_usage
+	}
+	if err := write_rle(stdout, p, active_rule); err != nil {
+		fmt.Fprintln(stderr, "life apgcode:", err)
+		return exit_error
+	}
+	return exit_ok
+}
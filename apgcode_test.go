@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// коды объектов каталога, как в Catagolue
+var catalog_apgcodes = map[string]string{
+	"block":            "xs4_33",
+	"beehive":          "xs6_696",
+	"loaf":             "xs7_2596",
+	"boat":             "xs5_253",
+	"ship":             "xs6_356",
+	"tub":              "xs4_252",
+	"pond":             "xs8_6996",
+	"long boat":        "xs7_25ac",
+	"barge":            "xs6_25a4",
+	"mango":            "xs8_69ic",
+	"eater 1":          "xs7_178c",
+	"aircraft carrier": "xs6_39c",
+	"snake":            "xs6_bd",
+	"blinker":          "xp2_7",
+	"toad":             "xp2_7e",
+	"beacon":           "xp2_318c",
+	"pulsar":           "xp3_co9nas0san9oczgoldlo0oldlogz1047210127401",
+	"pentadecathlon":   "xp15_4r4z4r4",
+	"glider":           "xq4_153",
+	"LWSS":             "xq4_6frc",
+	"MWSS":             "xq4_27dee6",
+	"HWSS":             "xq4_27deee6",
+}
+
+// объект каталога -> apgcode -> шаблон -> тот же объект и тот же код
+func TestApgcodeRoundTrip(t *testing.T) {
+	if len(catalog_apgcodes) != len(object_catalog) {
+		t.Errorf("%d codes for %d catalog objects", len(catalog_apgcodes), len(object_catalog))
+	}
+	for name, rle := range object_catalog {
+		p, err := parse_rle(strings.NewReader("x = 0, y = 0\n" + rle))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var object = canonical_object(p.cells)
+		if object.apgcode != catalog_apgcodes[name] {
+			t.Errorf("%s: apgcode %s, want %s", name, object.apgcode, catalog_apgcodes[name])
+		}
+
+		parsed, err := parse_apgcode(object.apgcode)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var back = classify_object(parsed.cells)
+		if back.apgcode != object.apgcode || back.key != object.key || back.name != name {
+			t.Errorf("%s: %s reads back as %s %q", name, object.apgcode, back.apgcode, back.name)
+		}
+	}
+}
+
+func TestParseApgcodeErrors(t *testing.T) {
+	for _, code := range []string{"", "block", "xs4", "xs4_", "ab4_33", "xsq_33", "xs4_3y", "xs4_3!"} {
+		if _, err := parse_apgcode(code); err == nil {
+			t.Errorf("%q: no error", code)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// файлы восстановления лежат в папке настроек пользователя: ~/.config/life на Linux, %AppData%\life на Windows
+func recovery_dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "life")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// у каждого запущенного окна свой файл с PID в имени, чтобы окна не затирали сессии друг друга
+func recovery_file() (string, error) {
+	dir, err := recovery_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("recovery-%d.session", os.Getpid())), nil
+}
+
+// файл восстановления чужого окна, которое уже не работает; recovery.session без PID остался от старых версий
+func is_orphan_recovery(filename string) bool {
+	var name = strings.TrimSuffix(filepath.Base(filename), ".session")
+	if name == "recovery" {
+		return true
+	}
+	pid, err := strconv.Atoi(strings.TrimPrefix(name, "recovery-"))
+	if err != nil {
+		return false
+	}
+	return pid != os.Getpid() && !process_alive(pid)
+}
+
+// автосохранение: поле копируем в Update, а сжатие и запись идут в отдельной горутине
+func (g *MyGame) autosave() {
+	// забираем результат прошлой записи, если она закончилась
+	select {
+	case err := <-g.autosave_done:
+		g.autosave_busy = false
+		if err != nil {
+			log.Println("autosave:", err)
+		}
+	default:
+	}
+
+	if g.autosave_interval == 0 || g.autosave_busy || g.recovery_offer != "" || time.Since(g.last_autosave) < g.autosave_interval {
+		return
+	}
+	g.last_autosave = time.Now()
+	g.autosave_busy = true
+
+	var s, field = g.capture_session()
+	go func() {
+		filename, err := recovery_file()
+		if err == nil {
+			err = s.set_board(field)
+		}
+		if err == nil {
+			err = save_session_file(filename, s)
+		}
+		g.autosave_done <- err
+	}()
+}
+
+// если Update или Draw упал, успеваем сохранить сессию и падаем дальше
+func (g *MyGame) save_on_panic() {
+	var r = recover()
+	if r == nil {
+		return
+	}
+	// фоновое автосохранение пишет в тот же временный файл: ждем его, иначе записи перемешаются
+	if g.autosave_busy {
+		<-g.autosave_done
+		g.autosave_busy = false
+	}
+	g.save_recovery()
+	panic(r)
+}
+
+// сохраняем сессию после падения; если состояние испорчено и упадет само сохранение,
+// вторая паника не должна спрятать первую
+func (g *MyGame) save_recovery() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("autosave: session not saved:", r)
+		}
+	}()
+	if filename, err := recovery_file(); err == nil {
+		if err := g.save_session(filename); err != nil {
+			log.Println("autosave:", err)
+		}
+	}
+}
+
+// файл восстановления остался от окна, которое уже не работает, значит, оно закончилось падением;
+// если таких несколько, предлагаем самый свежий, остальные - после ответа
+func (g *MyGame) check_recovery() {
+	dir, err := recovery_dir()
+	if err != nil {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "recovery*.session"))
+	if err != nil {
+		return
+	}
+	for _, filename := range files {
+		info, err := os.Stat(filename)
+		if err != nil || !is_orphan_recovery(filename) {
+			continue
+		}
+		if g.recovery_offer == "" || info.ModTime().After(g.recovery_time) {
+			g.recovery_offer = filename
+			g.recovery_time = info.ModTime()
+		}
+	}
+}
+
+// пока висит вопрос о восстановлении, остальные клавиши не работают; возвращает true, если вопрос еще открыт
+func (g *MyGame) recoveryKeyEvent() bool {
+	if g.recovery_offer == "" {
+		return false
+	}
+	var restore = inpututil.IsKeyJustPressed(ebiten.KeyY) || inpututil.IsKeyJustPressed(ebiten.KeyEnter)
+	var discard = inpututil.IsKeyJustPressed(ebiten.KeyN) || inpututil.IsKeyJustPressed(ebiten.KeyEscape)
+	if !restore && !discard {
+		return true
+	}
+	if restore {
+		if err := g.load_session(g.recovery_offer); err != nil {
+			log.Println(err)
+		}
+	}
+	// дальше сессия этого окна сохраняется в его собственный файл
+	os.Remove(g.recovery_offer)
+	g.recovery_offer = ""
+	g.last_autosave = time.Now()
+	g.check_recovery()
+	return g.recovery_offer != ""
+}
+
+func (g *MyGame) drawRecoveryOffer(screen *ebiten.Image) {
+	var text = fmt.Sprintf("The last session did not exit cleanly.\nAutosave from %s found.\n\nRestore it? Y / N",
+		g.recovery_time.Format("2006-01-02 15:04:05"))
+	ebitenutil.DebugPrintAt(screen, text, 20, screenHeight/2-40)
+}
+
+// при нормальном выходе дожидаемся записи и удаляем файл восстановления, он больше не нужен
+func (g *MyGame) finish_autosave() {
+	if g.autosave_busy {
+		<-g.autosave_done
+		g.autosave_busy = false
+	}
+	if filename, err := recovery_file(); err == nil {
+		os.Remove(filename)
+	}
+}
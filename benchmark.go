@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// дольше не считаем, если шаблон так и не устоялся
+	benchmark_max_gens = 10000
+	// поле считаем устоявшимся, если без кораблей оно повторяется с периодом до benchmark_max_period
+	// на протяжении benchmark_window поколений
+	benchmark_max_period = 60
+	benchmark_window     = 200
+)
+
+// долгожитель и то, чем он должен закончиться: поколение, с которого поле устоялось,
+// популяция в нем вместе с улетающими планерами и перепись, apgcode -> сколько раз
+type methuselah struct {
+	name       string
+	rle        string
+	lifespan   int
+	population int
+	census     map[string]int
+}
+
+var methuselahs = []methuselah{
+	{"R-pentomino", "b2o$2o$bo!", 1103, 116, map[string]int{
+		"xs4_33": 8, "xq4_153": 6, "xs6_696": 4, "xp2_7": 4, "xs5_253": 1, "xs7_2596": 1, "xs6_356": 1,
+	}},
+	{"acorn", "bo5b$3bo3b$2o2b3o!", 5206, 633, map[string]int{
+		"xp2_7": 41, "xs4_33": 34, "xs6_696": 30, "xq4_153": 13, "xs5_253": 8, "xs7_2596": 5, "xs6_356": 3,
+		"xs6_25a4": 2, "xs8_6996": 2, "xs8_69ic": 1,
+	}},
+	{"diehard", "6bob$2o6b$bo3b3o!", 130, 0, map[string]int{}},
+}
+
+// что получилось у одного долгожителя одним движком
+type benchmark_result struct {
+	lifespan   int
+	population int
+	census     map[string]int
+	stable     bool
+	// время только на шаги поля, без поиска повтора и переписи
+	elapsed time.Duration
+}
+
+// совпадает ли с ожидаемым; пустая строка - совпадает
+func (r benchmark_result) compare(m methuselah) string {
+	var problems = []string{}
+	if !r.stable {
+		problems = append(problems, "did not settle")
+	}
+	if r.lifespan != m.lifespan {
+		problems = append(problems, fmt.Sprintf("lifespan %d, expected %d", r.lifespan, m.lifespan))
+	}
+	if r.population != m.population {
+		problems = append(problems, fmt.Sprintf("population %d, expected %d", r.population, m.population))
+	}
+	if census_string(r.census) != census_string(m.census) {
+		problems = append(problems, fmt.Sprintf("census %s, expected %s", census_string(r.census), census_string(m.census)))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// перепись одной строкой: самые частые первыми, "xs4_33 8, xq4_153 6"
+func census_string(census map[string]int) string {
+	var codes = []string{}
+	for code := range census {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if census[codes[i]] != census[codes[j]] {
+			return census[codes[i]] > census[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	var parts = []string{}
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%s %d", code, census[code]))
+	}
+	if len(parts) == 0 {
+		return "empty"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// хеш поля без отдельно летящих кораблей: улетающие планеры не мешают заметить, что остальное устоялось
+func settled_hash(cells []POS) uint64 {
+	var rest = without_ships(cells)
+	sort.Slice(rest, func(i, j int) bool {
+		if rest[i].x != rest[j].x {
+			return rest[i].x < rest[j].x
+		}
+		return rest[i].y < rest[j].y
+	})
+	var hash = fnv.New64a()
+	hash.Write([]byte(cells_key(rest)))
+	return hash.Sum64()
+}
+
+// считаем долгожителя, пока поле без кораблей не начнет повторяться; lifespan - первое поколение повтора.
+// Улетевшие корабли стираем, чтобы поле не росло, но учитываем в популяции и переписи
+func run_methuselah(p pattern, max_gens int) benchmark_result {
+	var u = new_universe(p)
+	var result = benchmark_result{census: map[string]int{}}
+	var escapes = new_escape_detector(true)
+	var removed = 0
+	var hashes = []uint64{settled_hash(u.cells())}
+	var populations = []int{u.population()}
+	for u.generation < max_gens {
+		var start = time.Now()
+		u.step()
+		result.elapsed += time.Since(start)
+		if u.generation%escape_check_interval == 0 {
+			var escaped = u.observe_escapes(escapes)
+			for _, find := range escaped {
+				removed += len(find.cells)
+				result.census[canonical_object(find.cells).apgcode]++
+			}
+			if len(escaped) > 0 {
+				u.crop()
+			}
+		}
+		var cells = u.cells()
+		hashes = append(hashes, settled_hash(cells))
+		populations = append(populations, len(cells)+removed)
+		if len(cells) == 0 {
+			result.lifespan, result.stable = u.generation, true
+			return result
+		}
+		if period, ok := settled_period(hashes); ok {
+			// идем назад, пока поколения еще повторяются с тем же периодом
+			var s = len(hashes) - 1 - period
+			for s > 0 && hashes[s-1] == hashes[s-1+period] {
+				s--
+			}
+			result.lifespan, result.population, result.stable = s, populations[s], true
+			for _, entry := range take_census(cells, census_distance) {
+				result.census[entry.object.apgcode] += entry.count
+			}
+			return result
+		}
+	}
+	result.lifespan, result.population = u.generation, u.population()
+	return result
+}
+
+// последние benchmark_window поколений повторяются с каким-то периодом
+func settled_period(hashes []uint64) (int, bool) {
+	var n = len(hashes) - 1
+	for p := 1; p <= benchmark_max_period; p++ {
+		if n-benchmark_window-p < 0 {
+			break
+		}
+		var periodic = true
+		for i := 0; i < benchmark_window && periodic; i++ {
+			periodic = hashes[n-i] == hashes[n-i-p]
+		}
+		if periodic {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// life benchmark: считаем долгожителей каждым движком, сверяем итог с ожидаемым и печатаем время
+func benchmark_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life benchmark", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var engine_name = flags.String("engine", "", "engine to check, default: all of "+strings.Join(engine_names(), ", "))
+	var max_gens = flags.Int("gens", benchmark_max_gens, "give up on a pattern after this many generations")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	var names = engine_names()
+	if *engine_name != "" {
+		if _, ok := engines[*engine_name]; !ok {
+			fmt.Fprintf(stderr, "life benchmark: engine %q: expected one of %s\n", *engine_name, strings.Join(names, ", "))
+			return exit_usage
+		}
+		names = []string{*engine_name}
+	}
+	// долгожители известны для обычной Жизни на бесконечной плоскости
+	active_rule = conway_rule()
+	active_topology = topology_plane
+	var engine = active_engine
+	defer func() { active_engine = engine }()
+
+	var failed = 0
+	for _, name := range names {
+		active_engine = engines[name]
+		var total time.Duration
+		for _, m := range methuselahs {
+			p, err := parse_rle(strings.NewReader("x = 0, y = 0\n" + m.rle))
+			if err != nil {
+				fmt.Fprintln(stderr, "life benchmark:", err)
+				return exit_error
+			}
+			var result = run_methuselah(p, *max_gens)
+			total += result.elapsed
+			var status = "ok"
+			if problem := result.compare(m); problem != "" {
+				status = "FAIL: " + problem
+				failed++
+			}
+			fmt.Fprintf(stdout, "%-10s %-12s lifespan %5d  population %4d  %8s  %s\n",
+				name, m.name, result.lifespan, result.population, result.elapsed.Round(time.Millisecond), status)
+			fmt.Fprintf(stdout, "%-10s %-12s census %s\n", "", "", census_string(result.census))
+		}
+		fmt.Fprintf(stdout, "%-10s total %s\n", name, total.Round(time.Millisecond))
+	}
+	if failed > 0 {
+		fmt.Fprintf(stdout, "%d results differ from the expected\n", failed)
+		return exit_error
+	}
+	return exit_ok
+}
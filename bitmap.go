@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+)
+
+// клетка живая, если яркость пикселя ниже порога: рисуем черным по белому
+const bitmap_threshold = 0.5
+
+// читаем черно-белую картинку как шаблон, прозрачные пиксели считаем фоном
+func parse_bitmap(r io.Reader, threshold float64) (pattern, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return pattern{}, fmt.Errorf("bitmap: %w", err)
+	}
+	var bounds = img.Bounds()
+	var p = pattern{size_x: bounds.Dx(), size_y: bounds.Dy()}
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			var cr, cg, cb, ca = img.At(x, y).RGBA()
+			if ca < 0x8000 {
+				continue
+			}
+			// яркость по ITU-R BT.601, цвета в RGBA() уже умножены на альфу
+			var luma = (0.299*float64(cr) + 0.587*float64(cg) + 0.114*float64(cb)) / float64(ca)
+			if luma < threshold {
+				p.cells = append(p.cells, POS{x - bounds.Min.X, y - bounds.Min.Y})
+			}
+		}
+	}
+	return p, nil
+}
+
+// сохраняем клетки из прямоугольника box как PNG, клетка занимает cell на cell пикселей
+func save_snapshot(filename string, cells []POS, box bounding_box, cell int, t theme) error {
+	if box.empty {
+		box = bounding_box{0, 0, 0, 0, false}
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, render_cells(cells, box, cell, t, false)); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", filename, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,91 @@
+//go:build !nogui
+
+package main
+
+import (
+	"math/rand"
+)
+
+// очищаем поле и забываем историю
+func (g *MyGame) clear_board() {
+	for x := 0; x < g.height; x++ {
+		clear(g.field[x])
+	}
+	g.stats = life_stats{}
+	g.series = nil
+	g.snapshots = nil
+	g.is_pause = true
+	g.reset_period()
+	g.escapes.reset()
+}
+
+// ставим новый суп по текущим настройкам, один и тот же seed всегда дает один и тот же суп
+func (g *MyGame) new_soup() {
+	// суп больше игровой зоны не поместится, а случайному углу некуда будет сдвигаться
+	g.soup.size = min(g.soup.size, gameHeight, gameWidth)
+	var rng = rand.New(rand.NewSource(g.soup.seed))
+	var soup = generate_soup(g.soup, rng)
+	var n = g.soup.size
+
+	// левый верхний угол супа в координатах экрана (в клетках)
+	var corner = POS{(gameHeight - n) / 2, (gameWidth - n) / 2}
+	switch g.soup.placement {
+	case placement_cursor:
+		if in_game_area(g.cursor.x, g.cursor.y) {
+			corner = POS{g.cursor.x/scale - n/2, g.cursor.y/scale - n/2}
+		}
+	case placement_random:
+		corner = POS{rng.Intn(gameHeight - n + 1), rng.Intn(gameWidth - n + 1)}
+	}
+
+	g.clear_board()
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			var p = POS{g.x_offset + corner.x + x, g.y_offset + corner.y + y}
+			if soup[x][y] == 1 {
+				g.apply_cell(p, mode_draw)
+			}
+		}
+	}
+}
+
+// клавиши настройки и запуска супа
+func (g *MyGame) soupKeyEvent() {
+	if g.is_action_just_pressed(action_new_soup) {
+		g.new_soup()
+	}
+	if g.is_action_just_pressed(action_next_soup) {
+		g.soup.seed++
+		g.new_soup()
+	}
+	if g.is_action_just_pressed(action_soup_symmetry) {
+		g.soup.symmetry = (g.soup.symmetry + 1) % len(symmetry_names)
+	}
+	if g.is_action_just_pressed(action_soup_placement) {
+		g.soup.placement = (g.soup.placement + 1) % len(placement_names)
+	}
+	if g.is_action_just_pressed(action_soup_denser) {
+		g.soup.density = min(g.soup.density+0.05, 1)
+	}
+	if g.is_action_just_pressed(action_soup_sparser) {
+		g.soup.density = max(g.soup.density-0.05, 0)
+	}
+	if g.is_action_just_pressed(action_soup_bigger) {
+		g.soup.size = min(g.soup.size*2, gameHeight, gameWidth)
+	}
+	if g.is_action_just_pressed(action_soup_smaller) {
+		g.soup.size = max(g.soup.size/2, 1)
+	}
+}
+
+// ставим шаблон на поле так, чтобы его центр оказался в center (координаты поля)
+func (g *MyGame) place_pattern(p pattern, center POS) {
+	var corner = POS{center.x - p.size_x/2, center.y - p.size_y/2}
+	if active_topology == topology_plane {
+		corner = POS{max(corner.x, 0), max(corner.y, 0)}
+		g.ensure_field_size(corner.x+p.size_x, corner.y+p.size_y)
+	}
+	for _, cell := range p.cells {
+		g.apply_cell(POS{corner.x + cell.x, corner.y + cell.y}, mode_draw)
+	}
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// клетки на таком расстоянии (по Чебышеву) и ближе считаем одним объектом,
+	// более далекие объединяем, только если они влияют друг на друга
+	census_distance = 1
+	// дольше не ищем период одиночного объекта
+	max_object_period = 64
+	// объекты, которые ближе этого, проверяем: не влияют ли они друг на друга, как четверти пульсара
+	census_interaction_distance = 4
+	// столько поколений считаем пару объектов вместе и порознь
+	census_interaction_gens = 8
+)
+
+// известные объекты в RLE, имена показываем в переписи вместо описания
+var object_catalog = map[string]string{
+	"block":            "2o$2o!",
+	"beehive":          "b2o$o2bo$b2o!",
+	"loaf":             "b2o$o2bo$bobo$2bo!",
+	"boat":             "2o$obo$bo!",
+	"ship":             "2o$obo$b2o!",
+	"tub":              "bo$obo$bo!",
+	"pond":             "b2o$o2bo$o2bo$b2o!",
+	"long boat":        "2o$obo$bobo$2bo!",
+	"barge":            "bo$obo$bobo$2bo!",
+	"mango":            "b2o$o2bo$bo2bo$2b2o!",
+	"eater 1":          "2o$obo$2bo$2b2o!",
+	"aircraft carrier": "2o$o2bo$2b2o!",
+	"snake":            "2obo$ob2o!",
+	"blinker":          "3o!",
+	"toad":             "b3o$3o!",
+	"beacon":           "2o$2o$2b2o$2b2o!",
+	"pulsar":           "2b3o3b3o2$o4bobo4bo$o4bobo4bo$o4bobo4bo$2b3o3b3o2$2b3o3b3o$o4bobo4bo$o4bobo4bo$o4bobo4bo2$2b3o3b3o!",
+	"pentadecathlon":   "2bo4bo$2ob4ob2o$2bo4bo!",
+	"glider":           "bo$2bo$3o!",
+	"LWSS":             "bo2bo$o$o3bo$4o!",
+	"MWSS":             "3bo$bo3bo$o$o4bo$5o!",
+	"HWSS":             "3b2o$bo4bo$o$o5bo$6o!",
+}
+
+// объект переписи: каноническая форма, период и имя из каталога, если оно есть
+type census_object struct {
+	key     string // каноническая форма, одинаковая для всех фаз, поворотов и отражений
+	name    string
+	apgcode string // пустой для нестабильных и умерших объектов
+	result  period_result
+	cells   []POS // фаза с канонической формой, от угла (0, 0)
+}
+
+// имя из каталога или apgcode и описание того, что это за объект
+func (o census_object) label() string {
+	if o.name != "" && o.apgcode != "" {
+		return fmt.Sprintf("%s (%s)", o.name, o.apgcode)
+	}
+	if o.apgcode != "" {
+		return fmt.Sprintf("%s, %s", o.apgcode, o.description())
+	}
+	return o.description()
+}
+
+func (o census_object) description() string {
+	switch o.result.kind {
+	case period_unknown:
+		return fmt.Sprintf("unstable, %d cells", len(o.cells))
+	case period_spaceship:
+		// направление зависит от поворота, в описании его не пишем
+		return fmt.Sprintf("spaceship period %d, %d cells", o.result.period, len(o.cells))
+	}
+	return fmt.Sprintf("%s, %d cells", o.result, len(o.cells))
+}
+
+// сколько одинаковых объектов нашли
+type census_entry struct {
+	object census_object
+	count  int
+}
+
+// делим живые клетки на связные группы: соседи - клетки не дальше distance по каждой оси
+func find_objects(cells []POS, distance int) [][]POS {
+	var alive = map[POS]bool{}
+	for _, c := range cells {
+		alive[c] = true
+	}
+	var visited = map[POS]bool{}
+	var objects = [][]POS{}
+	for _, start := range cells {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		var object = []POS{}
+		var queue = []POS{start}
+		for len(queue) > 0 {
+			var c = queue[0]
+			queue = queue[1:]
+			object = append(object, c)
+			for dx := -distance; dx <= distance; dx++ {
+				for dy := -distance; dy <= distance; dy++ {
+					var n = POS{c.x + dx, c.y + dy}
+					if alive[n] && !visited[n] {
+						visited[n] = true
+						queue = append(queue, n)
+					}
+				}
+			}
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// следующее поколение клеток на бесконечной плоскости, для небольших объектов
+func step_cells(cells []POS) []POS {
+	var alive = map[POS]bool{}
+	var neighbours = map[POS]int{}
+	for _, c := range cells {
+		alive[c] = true
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx != 0 || dy != 0 {
+					neighbours[POS{c.x + dx, c.y + dy}]++
+				}
+			}
+		}
+	}
+	var next = []POS{}
+	for c, n := range neighbours {
+		if (alive[c] && active_rule.survive[n]) || (!alive[c] && active_rule.birth[n]) {
+			next = append(next, c)
+		}
+	}
+	return next
+}
+
+// клетки объекта в поколениях 0..gens; если он вымер раньше, история короче
+func cells_history(cells []POS, gens int) [][]POS {
+	var history = [][]POS{cells}
+	for len(history) <= gens && len(cells) > 0 {
+		cells = step_cells(cells)
+		history = append(history, cells)
+	}
+	return history
+}
+
+// два объекта влияют друг на друга: вместе они живут не так, как каждый по отдельности;
+// a и b - истории объектов из cells_history на census_interaction_gens поколений
+func objects_interact(a [][]POS, b [][]POS) bool {
+	var at = func(history [][]POS, i int) []POS {
+		if i < len(history) {
+			return history[i]
+		}
+		return nil
+	}
+	var together = append(append([]POS{}, a[0]...), b[0]...)
+	for i := 1; i <= census_interaction_gens; i++ {
+		together = step_cells(together)
+		var a, b = at(a, i), at(b, i)
+		if len(together) != len(a)+len(b) {
+			return true
+		}
+		var apart = map[POS]bool{}
+		for _, c := range a {
+			apart[c] = true
+		}
+		for _, c := range b {
+			apart[c] = true
+		}
+		for _, c := range together {
+			if !apart[c] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// объединяем близкие объекты, которые влияют друг на друга: так, как в apgsearch, пульсар
+// остается одним объектом, а два блока через клетку - двумя. Кусок, который сам по себе вымирает
+// (как одна клетка в фазе LWSS или половина пентадекатлона), присоединяем к ближайшему объекту:
+// на устоявшемся поле отдельным объектом он быть не может
+func merge_interacting(objects [][]POS) [][]POS {
+	var boxes = make([]bounding_box, len(objects))
+	var histories = make([][][]POS, len(objects))
+	for i, object := range objects {
+		boxes[i] = cells_box(object)
+		histories[i] = cells_history(object, census_interaction_gens)
+	}
+	// каждую пару проверяем один раз, объединения собираем в лесе непересекающихся множеств
+	var groups = new_union_find(len(objects))
+	for i := range objects {
+		for j := i + 1; j < len(objects); j++ {
+			if boxes_within(boxes[i], boxes[j], census_interaction_distance) && objects_interact(histories[i], histories[j]) {
+				groups.union(i, j)
+			}
+		}
+	}
+	objects, boxes = groups.collect(objects, boxes)
+
+	// кусок живет lifetime поколений, а влияние идет со скоростью света,
+	// поэтому держать его может только объект не дальше чем через 2*lifetime клеток
+	groups = new_union_find(len(objects))
+	for i := range objects {
+		var lifetime, dies = lifetime_alone(objects[i])
+		if !dies {
+			continue
+		}
+		var nearest, nearest_distance = -1, 2*lifetime + 1
+		for j := range objects {
+			if j == i || !boxes_within(boxes[i], boxes[j], nearest_distance-1) {
+				continue
+			}
+			if d := cells_distance(objects[i], objects[j]); d < nearest_distance {
+				nearest, nearest_distance = j, d
+			}
+		}
+		if nearest >= 0 {
+			groups.union(i, nearest)
+		}
+	}
+	objects, _ = groups.collect(objects, boxes)
+	return objects
+}
+
+// прямоугольники не дальше distance клеток друг от друга
+func boxes_within(a bounding_box, b bounding_box, distance int) bool {
+	return a.min_x-b.max_x <= distance && b.min_x-a.max_x <= distance &&
+		a.min_y-b.max_y <= distance && b.min_y-a.max_y <= distance
+}
+
+// лес непересекающихся множеств: какие объекты уже объединены
+type union_find struct {
+	parent []int
+}
+
+func new_union_find(n int) union_find {
+	var u = union_find{parent: make([]int, n)}
+	for i := range u.parent {
+		u.parent[i] = i
+	}
+	return u
+}
+
+func (u union_find) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+	return i
+}
+
+func (u union_find) union(i int, j int) {
+	u.parent[u.find(i)] = u.find(j)
+}
+
+// склеиваем клетки и прямоугольники каждого множества, порядок - по первому объекту множества
+func (u union_find) collect(objects [][]POS, boxes []bounding_box) ([][]POS, []bounding_box) {
+	var index = map[int]int{}
+	var merged = [][]POS{}
+	var merged_boxes = []bounding_box{}
+	for i, object := range objects {
+		var root = u.find(i)
+		k, ok := index[root]
+		if !ok {
+			index[root] = len(merged)
+			merged = append(merged, nil)
+			merged_boxes = append(merged_boxes, boxes[i])
+			k = len(merged) - 1
+		}
+		merged[k] = append(merged[k], object...)
+		merged_boxes[k] = merged_boxes[k].extend(boxes[i])
+	}
+	return merged, merged_boxes
+}
+
+// за сколько поколений клетки без остального поля вымирают, если это случается до max_object_period
+func lifetime_alone(cells []POS) (int, bool) {
+	var previous = [][]POS{}
+	for i := 0; i < max_object_period; i++ {
+		if len(cells) == 0 {
+			return i, true
+		}
+		// натюрморты и осцилляторы периода 2 - почти весь мусор на поле - дальше не считаем
+		for _, p := range previous {
+			if same_cells(p, cells) {
+				return 0, false
+			}
+		}
+		previous = append(previous[max(len(previous)-1, 0):], cells)
+		cells = step_cells(cells)
+	}
+	return 0, false
+}
+
+// одни и те же клетки, порядок не важен
+func same_cells(a []POS, b []POS) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var alive = map[POS]bool{}
+	for _, c := range a {
+		alive[c] = true
+	}
+	for _, c := range b {
+		if !alive[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// наименьшее расстояние (по Чебышеву) между клетками двух объектов
+func cells_distance(a []POS, b []POS) int {
+	var distance = -1
+	for _, p := range a {
+		for _, q := range b {
+			if d := max(p.x-q.x, q.x-p.x, p.y-q.y, q.y-p.y); distance < 0 || d < distance {
+				distance = d
+			}
+		}
+	}
+	return distance
+}
+
+// восемь поворотов и отражений клетки
+var object_transforms = []func(p POS) POS{
+	func(p POS) POS { return POS{p.x, p.y} },
+	func(p POS) POS { return POS{-p.x, p.y} },
+	func(p POS) POS { return POS{p.x, -p.y} },
+	func(p POS) POS { return POS{-p.x, -p.y} },
+	func(p POS) POS { return POS{p.y, p.x} },
+	func(p POS) POS { return POS{-p.y, p.x} },
+	func(p POS) POS { return POS{p.y, -p.x} },
+	func(p POS) POS { return POS{-p.y, -p.x} },
+}
+
+// сдвигаем клетки к углу (0, 0) и сортируем по строкам
+func normalize_cells(cells []POS) []POS {
+	if len(cells) == 0 {
+		return nil
+	}
+	var min_x, min_y = cells[0].x, cells[0].y
+	for _, c := range cells {
+		min_x = min(min_x, c.x)
+		min_y = min(min_y, c.y)
+	}
+	var result = make([]POS, len(cells))
+	for i, c := range cells {
+		result[i] = POS{c.x - min_x, c.y - min_y}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].y != result[j].y {
+			return result[i].y < result[j].y
+		}
+		return result[i].x < result[j].x
+	})
+	return result
+}
+
+func cells_key(cells []POS) string {
+	var key strings.Builder
+	for _, c := range cells {
+		key.WriteString(strconv.Itoa(c.x))
+		key.WriteByte(',')
+		key.WriteString(strconv.Itoa(c.y))
+		key.WriteByte(';')
+	}
+	return key.String()
+}
+
+// считаем объект отдельно от остального поля, пока он не повторится, и собираем его фазы
+func object_phases(cells []POS) ([][]POS, period_result) {
+	return pattern_phases(cells, max_object_period)
+}
+
+// фазы одного периода, если шаблон повторился не позже чем через max_period поколений
+func pattern_phases(cells []POS, max_period int) ([][]POS, period_result) {
+	var p = pattern{cells: normalize_cells(cells)}
+	for _, c := range p.cells {
+		p.size_x = max(p.size_x, c.x+1)
+		p.size_y = max(p.size_y, c.y+1)
+	}
+
+	// объект считаем на бесконечной плоскости, даже если поле - тор
+	var u = new_plane_universe(p)
+	var detector = new_period_detector(max_period + 1)
+	var phases = [][]POS{u.cells()}
+	var result = u.observe(detector)
+	for !result.stable() && u.generation < max_period {
+		u.step()
+		result = u.observe(detector)
+		phases = append(phases, u.cells())
+	}
+	if result.kind == period_died || !result.stable() {
+		return phases[:1], result
+	}
+	// последняя фаза повторяет одну из прошлых, берем только фазы одного периода
+	return phases[len(phases)-1-result.period : len(phases)-1], result
+}
+
+// объект с именем из каталога
+func classify_object(cells []POS) census_object {
+	var object = canonical_object(cells)
+	object.name = catalog_names()[object.key]
+	return object
+}
+
+// каноническая форма: наименьшая из всех фаз, поворотов и отражений
+func canonical_object(cells []POS) census_object {
+	var phases, result = object_phases(cells)
+	var object = census_object{result: result}
+	for _, phase := range phases {
+		for _, transform := range object_transforms {
+			var moved = make([]POS, len(phase))
+			for i, c := range phase {
+				moved[i] = transform(c)
+			}
+			moved = normalize_cells(moved)
+			var key = cells_key(moved)
+			if object.key == "" || len(moved) < len(object.cells) || (len(moved) == len(object.cells) && key < object.key) {
+				object.key = key
+				object.cells = moved
+			}
+		}
+	}
+	object.apgcode = apgcode(phases, result)
+	return object
+}
+
+// каталог зависит от правила, поэтому ключи считаем для каждого правила отдельно
+var (
+	catalog_cache = map[string]map[string]string{}
+	catalog_mutex sync.Mutex
+)
+
+func catalog_names() map[string]string {
+	catalog_mutex.Lock()
+	defer catalog_mutex.Unlock()
+	var rule = active_rule.String()
+	if names, ok := catalog_cache[rule]; ok {
+		return names
+	}
+	var names = map[string]string{}
+	catalog_cache[rule] = names
+	for name, rle := range object_catalog {
+		p, err := parse_rle(strings.NewReader("x = 0, y = 0\n" + rle))
+		if err != nil {
+			continue
+		}
+		var object = canonical_object(p.cells)
+		// при другом правиле объект может и не жить, такие имена не показываем
+		if object.result.kind != period_unknown && object.result.kind != period_died {
+			names[object.key] = name
+		}
+	}
+	return names
+}
+
+// перепись: какие объекты и сколько раз встречаются, самые частые первыми
+func take_census(cells []POS, distance int) []census_entry {
+	var entries = map[string]*census_entry{}
+	// одинаковые объекты в одинаковой фазе не считаем заново
+	var known = map[string]census_object{}
+	for _, cells := range merge_interacting(find_objects(cells, distance)) {
+		var raw = cells_key(normalize_cells(cells))
+		object, ok := known[raw]
+		if !ok {
+			object = classify_object(cells)
+			known[raw] = object
+		}
+		if entry, ok := entries[object.key]; ok {
+			entry.count++
+		} else {
+			entries[object.key] = &census_entry{object, 1}
+		}
+	}
+
+	var result = []census_entry{}
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].object.label() < result[j].object.label()
+	})
+	return result
+}
+
+// сохраняем объекты без имени в каталоге, каждый в свой RLE файл; осколки, которые поодиночке умирают, пропускаем
+func export_unknown_objects(dir string, census []census_entry) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	var saved = 0
+	for _, entry := range census {
+		if entry.object.name != "" || entry.object.result.kind == period_died {
+			continue
+		}
+		var p = pattern{cells: entry.object.cells}
+		for _, c := range p.cells {
+			p.size_x = max(p.size_x, c.x+1)
+			p.size_y = max(p.size_y, c.y+1)
+		}
+		saved++
+		var filename = filepath.Join(dir, fmt.Sprintf("unknown_%d.rle", saved))
+		file, err := os.Create(filename)
+		if err != nil {
+			return saved - 1, err
+		}
+		fmt.Fprintf(file, "#C %s, found %d times\n", entry.object.label(), entry.count)
+		if err := write_rle(file, p, active_rule); err != nil {
+			file.Close()
+			return saved - 1, fmt.Errorf("write %s: %w", filename, err)
+		}
+		if err := file.Close(); err != nil {
+			return saved - 1, err
+		}
+	}
+	return saved, nil
+}
+
+// life census: считаем поле и печатаем, какие объекты на нем остались
+func census_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life census", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var gens = flags.Int("gens", 2000, "maximum number of generations before the census")
+	var distance = flags.Int("distance", census_distance, "cells this close belong to one object")
+	var export_dir = flags.String("export", "", "save objects missing from the catalog as RLE files into this directory")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	if *distance < 1 {
+		fmt.Fprintln(stderr, "life census: distance must be at least 1")
+		return exit_usage
+	}
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life census:", err)
+		return exit_usage
+	}
+
+	var u = new_universe(start)
+	var detector = new_board_detector()
+	var result = u.observe(detector)
+	for u.generation < *gens && !result.stable() {
+		u.step()
+		result = u.observe(detector)
+	}
+
+	var census = take_census(u.cells(), *distance)
+	fmt.Fprintf(stdout, "#C generation %d, %s\n", u.generation, result)
+	for _, entry := range census {
+		fmt.Fprintf(stdout, "%d\t%s\n", entry.count, entry.object.label())
+	}
+	if *export_dir != "" {
+		saved, err := export_unknown_objects(*export_dir, census)
+		if err != nil {
+			fmt.Fprintln(stderr, "life census:", err)
+			return exit_error
+		}
+		fmt.Fprintf(stdout, "#C %d unknown objects saved to %s\n", saved, *export_dir)
+	}
+	return exit_ok
+}
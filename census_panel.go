@@ -0,0 +1,116 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// куда сохраняем объекты, которых нет в каталоге
+	census_dir = "census"
+	// сколько строк переписи помещается на панели
+	census_panel_lines = 20
+	census_panel_y     = 20
+	census_panel_width = 300
+)
+
+// перепись, посчитанная в фоне, и поколение, для которого ее считали
+type census_update struct {
+	generation int
+	census     []census_entry
+}
+
+// пересчитываем перепись не чаще раза в секунду и только если поле поменялось;
+// на большом поле это доли секунды, поэтому считаем в горутине, как анализ излучателя
+func (g *MyGame) update_census() {
+	if !g.show_census {
+		return
+	}
+	select {
+	case update := <-g.census_done:
+		g.census_busy = false
+		g.census, g.census_generation = update.census, update.generation
+	default:
+	}
+	if g.census_busy || (g.census != nil && (g.census_generation == g.stats.generation || time.Since(g.census_time) < time.Second)) {
+		return
+	}
+	var cells, generation, distance = g.board_universe().cells(), g.stats.generation, g.census_distance
+	g.census_busy = true
+	g.census_time = time.Now()
+	go func() {
+		g.census_done <- census_update{generation, take_census(cells, distance)}
+	}()
+}
+
+// клетки снимаем сразу, перепись и запись файлов можно делать в горутине
+func export_census(dir string, cells []POS, distance int) error {
+	var census = take_census(cells, distance)
+	saved, err := export_unknown_objects(dir, census)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d unknown objects saved to %s", saved, dir)
+	return nil
+}
+
+// список объектов в левом верхнем углу, самые частые сверху, по щелчку объект можно поставить на поле
+func (g *MyGame) drawCensus(screen *ebiten.Image) {
+	var lines = []string{fmt.Sprintf("Census at generation %d:", g.census_generation)}
+	if g.census == nil {
+		lines[0] = "Census: counting..."
+	}
+	for i, entry := range g.census {
+		if i == census_panel_lines {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(g.census)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%5d  %s", entry.count, entry.object.label()))
+	}
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(screen, line, 0, census_panel_y+i*hud_line_height)
+	}
+}
+
+// клетки шаблона как фигура для paintFigure, центр шаблона - под курсором
+func (p pattern) to_pixels() []PIXEL {
+	var pixels = []PIXEL{}
+	for _, c := range p.cells {
+		pixels = append(pixels, PIXEL{c.x - p.size_x/2, c.y - p.size_y/2, 1})
+	}
+	return pixels
+}
+
+// щелчок по строке переписи берет объект в руку, следующий щелчок по полю его ставит
+func (g *MyGame) handle_census_click(mx, my int) bool {
+	if !g.show_census || g.show_help || mx < 0 || mx >= census_panel_width {
+		return false
+	}
+	var idx = (my-census_panel_y)/hud_line_height - 1
+	if my < census_panel_y || idx < -1 || idx >= min(len(g.census), census_panel_lines) {
+		return false
+	}
+	if idx >= 0 && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		var object = g.census[idx].object
+		var p = pattern{cells: object.cells}
+		if object.apgcode != "" {
+			if decoded, err := parse_apgcode(object.apgcode); err == nil {
+				p = decoded
+			}
+		}
+		for _, c := range p.cells {
+			p.size_x = max(p.size_x, c.x+1)
+			p.size_y = max(p.size_y, c.y+1)
+		}
+		g.pixels = p.to_pixels()
+		g.is_figure_draw = true
+	}
+	return true
+}
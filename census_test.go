@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// каждая фаза объекта каталога - один объект с его именем, даже когда фаза распадается на куски
+func TestCensusCatalogPhases(t *testing.T) {
+	for name, rle := range object_catalog {
+		p, err := parse_rle(strings.NewReader("x = 0, y = 0\n" + rle))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var phases, _ = pattern_phases(p.cells, max_object_period)
+		for i, phase := range phases {
+			var census = take_census(phase, census_distance)
+			if len(census) != 1 {
+				t.Errorf("%s, phase %d: %d kinds of objects", name, i, len(census))
+			} else if census[0].count != 1 || census[0].object.name != name {
+				t.Errorf("%s, phase %d: %d of %s", name, i, census[0].count, census[0].object.label())
+			}
+		}
+	}
+}
+
+// два блока через клетку друг на друга не влияют
+func TestCensusBiBlock(t *testing.T) {
+	var cells = []POS{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {3, 0}, {3, 1}, {4, 0}, {4, 1}}
+	var census = take_census(cells, census_distance)
+	if len(census) != 1 {
+		t.Fatalf("%d kinds of objects", len(census))
+	}
+	if census[0].count != 2 || census[0].object.name != "block" {
+		t.Errorf("%d of %s", census[0].count, census[0].object.label())
+	}
+}
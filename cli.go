@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// коды выхода подкоманд
+const (
+	exit_ok         = 0 // подкоманда отработала, о поле ничего не сообщает
+	exit_running    = 0 // поле еще живет и меняется
+	exit_error      = 1
+	exit_usage      = 2
+	exit_died_out   = 3 // живых клеток не осталось
+	exit_stabilized = 4 // поле повторяет одно из прошлых поколений
+)
+
+// сколько последних поколений сравниваем, чтобы заметить повтор
+const stability_window = 1024
+
+// подкоманды без окна, args без имени программы
+func run_subcommand(args []string, stdout io.Writer, stderr io.Writer) (bool, int) {
+	if len(args) == 0 {
+		return false, 0
+	}
+	switch args[0] {
+	case "run":
+		return true, run_command(args[1:], stdout, stderr)
+	case "tui":
+		return true, tui_command(args[1:], stdout, stderr)
+	case "animate":
+		return true, animate_command(args[1:], stdout, stderr)
+	case "census":
+		return true, census_command(args[1:], stdout, stderr)
+	case "apgcode":
+		return true, apgcode_command(args[1:], stdout, stderr)
+	case "search":
+		return true, search_command(args[1:], stdout, stderr)
+	case "emitter":
+		return true, emitter_command(args[1:], stdout, stderr)
+	case "metrics":
+		return true, metrics_command(args[1:], stdout, stderr)
+	case "predecessor":
+		return true, predecessor_command(args[1:], stdout, stderr)
+	case "benchmark":
+		return true, benchmark_command(args[1:], stdout, stderr)
+	case "find":
+		return true, find_command(args[1:], stdout, stderr)
+	}
+	return false, 0
+}
+
+func engine_names() []string {
+	var names = []string{}
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// настройки стартового поля для подкоманд: файл шаблона или суп
+type start_options struct {
+	pattern_file string
+	rule         string
+	engine       string
+	soup         soup_settings
+	symmetry     string
+}
+
+// общие флаги подкоманд: шаблон или суп, правило и движок
+func (o *start_options) register(flags *flag.FlagSet) {
+	var defaults = default_soup_settings(1)
+	flags.StringVar(&o.pattern_file, "pattern", "", "RLE or .cells pattern (default: seeded soup)")
+	flags.Int64Var(&o.soup.seed, "seed", defaults.seed, "soup seed")
+	flags.Float64Var(&o.soup.density, "density", defaults.density, "soup density 0..1")
+	flags.IntVar(&o.soup.size, "size", defaults.size, "soup size")
+	flags.StringVar(&o.symmetry, "symmetry", symmetry_names[defaults.symmetry], strings.Join(symmetry_names, ", "))
+	flags.StringVar(&o.rule, "rule", "B3/S23", "rule in B/S notation")
+	flags.StringVar(&o.engine, "engine", default_engine, strings.Join(engine_names(), ", "))
+}
+
+// включаем правило и движок и строим стартовый шаблон
+func (o *start_options) apply() (pattern, error) {
+	rule, err := parse_rule(o.rule)
+	if err != nil {
+		return pattern{}, err
+	}
+	engine, ok := engines[o.engine]
+	if !ok {
+		return pattern{}, fmt.Errorf("engine %q: expected one of %s", o.engine, strings.Join(engine_names(), ", "))
+	}
+	active_rule = rule
+	active_engine = engine
+	active_topology = topology_plane
+
+	if o.pattern_file != "" {
+		return load_pattern(o.pattern_file)
+	}
+
+	symmetry, err := parse_symmetry(o.symmetry)
+	if err != nil {
+		return pattern{}, err
+	}
+	o.soup.symmetry = symmetry
+	if o.soup.size < 1 {
+		return pattern{}, fmt.Errorf("soup size %d: expected at least 1", o.soup.size)
+	}
+	if o.soup.density < 0 || o.soup.density > 1 {
+		return pattern{}, fmt.Errorf("soup density %g: expected 0..1", o.soup.density)
+	}
+	var soup = generate_soup(o.soup, rand.New(rand.NewSource(o.soup.seed)))
+	var n = o.soup.size
+	return pattern_from_field(soup, bounding_box{0, 0, n - 1, n - 1, false}), nil
+}
+
+// life run: считаем N поколений без окна и печатаем итог в RLE
+func run_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var gens = flags.Int("gens", 1000, "number of generations")
+	var stop = flags.Bool("stop", true, "stop as soon as the board dies out or repeats")
+	var png_file = flags.String("png", "", "also save the last generation as PNG")
+	var png_cell = flags.Int("png-cell", 1, "PNG pixels per cell")
+	var escapes = flags.Bool("escapes", false, "count gliders and other spaceships leaving the pattern")
+	var remove_escapes = flags.Bool("remove-escapes", false, "count escaping spaceships and delete them")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: life run [flags]")
+		fmt.Fprintln(stderr, "exit codes: 0 still running, 3 died out, 4 stabilized, 1 error, 2 bad usage")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life run:", err)
+		return exit_usage
+	}
+	if *png_cell < 1 || *png_cell > 64 {
+		fmt.Fprintf(stderr, "life run: png cell size %d: expected 1..64\n", *png_cell)
+		return exit_usage
+	}
+
+	var u = new_universe(start)
+	var detector = new_board_detector()
+	var escaped = new_escape_detector(*remove_escapes)
+	var result = u.observe(detector)
+	for u.generation < *gens && !(*stop && result.stable()) {
+		u.step()
+		if (*escapes || *remove_escapes) && u.generation%escape_check_interval == 0 {
+			u.observe_escapes(escaped)
+		}
+		result = u.observe(detector)
+	}
+
+	var status, code = result.String(), exit_stabilized
+	switch result.kind {
+	case period_unknown:
+		status, code = "still running", exit_running
+	case period_died:
+		code = exit_died_out
+	}
+
+	fmt.Fprintf(stdout, "#C generation %d\n", u.generation)
+	fmt.Fprintf(stdout, "#C population %d\n", u.population())
+	fmt.Fprintf(stdout, "#C bounding box %s\n", u.box())
+	fmt.Fprintf(stdout, "#C status %s\n", status)
+	if *escapes || *remove_escapes {
+		fmt.Fprintf(stdout, "#C escaped %s\n", escaped)
+	}
+	if err := write_rle(stdout, u.pattern(), active_rule); err != nil {
+		fmt.Fprintln(stderr, "life run:", err)
+		return exit_error
+	}
+	if *png_file != "" {
+		var box = u.box()
+		box = bounding_box{box.min_x - u.origin.x, box.min_y - u.origin.y, box.max_x - u.origin.x, box.max_y - u.origin.y, box.empty}
+		if err := save_snapshot(*png_file, u.cells(), box, *png_cell, themes["mono"]); err != nil {
+			fmt.Fprintln(stderr, "life run:", err)
+			return exit_error
+		}
+	}
+	return code
+}
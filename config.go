@@ -0,0 +1,264 @@
+//go:build !nogui
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// конфиг, который читаем, если -config не указан
+const default_config_file = "life.json"
+
+// настройки запуска: из файла конфига, поверх них флаги командной строки
+type app_config struct {
+	WindowWidth  int     `json:"window_width"`
+	WindowHeight int     `json:"window_height"`
+	CellSize     int     `json:"cell_size"`
+	Rule         string  `json:"rule"`
+	Topology     string  `json:"topology"`
+	SoupDensity  float64 `json:"soup_density"`
+	SoupSize     int     `json:"soup_size"`
+	SoupSymmetry string  `json:"soup_symmetry"`
+	Seed         *int64  `json:"seed"`
+	Pattern      string  `json:"pattern"`
+	Speed        int     `json:"speed"`
+	Theme        string  `json:"theme"`
+	Keymap       string  `json:"keymap"`
+	AutoPause    bool    `json:"auto_pause"`
+	// стирать корабли, улетевшие от основной массы, чтобы поле не росло бесконечно
+	RemoveEscapes bool `json:"remove_escapes"`
+	// клетки на таком расстоянии и ближе в переписи считаются одним объектом
+	CensusDistance int `json:"census_distance"`
+	// как часто сохранять сессию для восстановления после падения, в секундах, 0 - не сохранять
+	Autosave int `json:"autosave"`
+	// начать с супа из зерна Seed, так открываются супы из отчета life search
+	StartSoup bool `json:"start_soup"`
+	// запись анимации клавишей, настройки как у life animate; поколения считаются от текущего,
+	// клетка 0 - как на экране, пустая тема - цвета экрана,
+	// область: пустая - видимая часть поля, "all" - все клетки, или x,y,w,h в клетках поля
+	AnimationFile  string `json:"animation_file"`
+	AnimationFrom  int    `json:"animation_from"`
+	AnimationTo    int    `json:"animation_to"`
+	AnimationCell  int    `json:"animation_cell"`
+	AnimationDelay int    `json:"animation_delay"`
+	AnimationTheme string `json:"animation_theme"`
+	AnimationGrid  bool   `json:"animation_grid"`
+	AnimationCrop  string `json:"animation_crop"`
+}
+
+func default_config() app_config {
+	return app_config{
+		WindowWidth:    700,
+		WindowHeight:   640,
+		CellSize:       4,
+		Rule:           "B3/S23",
+		Topology:       topology_plane,
+		SoupDensity:    default_soup_density,
+		SoupSize:       default_soup_size,
+		SoupSymmetry:   symmetry_names[symmetry_c1],
+		Speed:          speed_level_slow,
+		Theme:          "light",
+		Keymap:         keymap_file,
+		CensusDistance: census_distance,
+		Autosave:       60,
+		AnimationFile:  animation_file,
+		AnimationTo:    animation_frames - 1,
+		AnimationDelay: animation_delay,
+	}
+}
+
+// читаем конфиг, незнакомые ключи считаем ошибкой, чтобы опечатки не терялись
+func load_config(filename string, cfg *app_config) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// проверяем значения, в ошибке пишем, какое поле и что с ним не так
+func (cfg app_config) validate() error {
+	var errs = []error{}
+	if cfg.WindowWidth < sidebar_width+100 || cfg.WindowHeight < 100 {
+		errs = append(errs, fmt.Errorf("window size %dx%d is too small, need at least %dx100", cfg.WindowWidth, cfg.WindowHeight, sidebar_width+100))
+	}
+	if cfg.CellSize < 1 || cfg.CellSize > 32 {
+		errs = append(errs, fmt.Errorf("cell size %d: expected 1..32", cfg.CellSize))
+	}
+	if _, err := parse_rule(cfg.Rule); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parse_topology(cfg.Topology); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.SoupDensity < 0 || cfg.SoupDensity > 1 {
+		errs = append(errs, fmt.Errorf("soup density %g: expected 0..1", cfg.SoupDensity))
+	}
+	if cfg.SoupSize < 1 {
+		errs = append(errs, fmt.Errorf("soup size %d: expected at least 1", cfg.SoupSize))
+	} else if cfg.CellSize >= 1 && cfg.SoupSize > max_soup_size(cfg.WindowWidth, cfg.WindowHeight, cfg.CellSize) {
+		errs = append(errs, fmt.Errorf("soup size %d: expected 1..%d for this window and cell size",
+			cfg.SoupSize, max_soup_size(cfg.WindowWidth, cfg.WindowHeight, cfg.CellSize)))
+	}
+	if _, err := parse_symmetry(cfg.SoupSymmetry); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Speed < 0 || cfg.Speed >= len(speed_levels) {
+		errs = append(errs, fmt.Errorf("speed %d: expected 0..%d", cfg.Speed, len(speed_levels)-1))
+	}
+	if _, ok := themes[cfg.Theme]; !ok {
+		errs = append(errs, fmt.Errorf("theme %q: expected one of %s", cfg.Theme, strings.Join(theme_names(), ", ")))
+	}
+	if _, err := load_keymap(cfg.Keymap); err != nil {
+		errs = append(errs, fmt.Errorf("keymap: %w", err))
+	}
+	if cfg.CensusDistance < 1 || cfg.CensusDistance > 8 {
+		errs = append(errs, fmt.Errorf("census distance %d: expected 1..8", cfg.CensusDistance))
+	}
+	if cfg.Autosave < 0 {
+		errs = append(errs, fmt.Errorf("autosave interval %d: expected 0 or more seconds", cfg.Autosave))
+	}
+	if _, err := cfg.window_animation(); err != nil {
+		errs = append(errs, fmt.Errorf("animation: %w", err))
+	}
+	if cfg.Pattern != "" && !is_apgcode(cfg.Pattern) {
+		if _, err := os.Stat(cfg.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("pattern: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// разбираем флаги: сначала значения по умолчанию, потом файл конфига, потом явно заданные флаги
+func parse_command_line(name string, args []string, output io.Writer) (app_config, error) {
+	var cfg = default_config()
+	var flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+
+	var config_file = flags.String("config", default_config_file, "JSON config file")
+	var from_flags = default_config()
+	var seed int64
+	flags.IntVar(&from_flags.WindowWidth, "width", from_flags.WindowWidth, "window width in pixels")
+	flags.IntVar(&from_flags.WindowHeight, "height", from_flags.WindowHeight, "window height in pixels")
+	flags.IntVar(&from_flags.CellSize, "cell", from_flags.CellSize, "cell size in pixels")
+	flags.StringVar(&from_flags.Rule, "rule", from_flags.Rule, "rule in B/S notation")
+	flags.StringVar(&from_flags.Topology, "topology", from_flags.Topology, "plane or torus")
+	flags.Float64Var(&from_flags.SoupDensity, "density", from_flags.SoupDensity, "soup density 0..1")
+	flags.IntVar(&from_flags.SoupSize, "soup-size", from_flags.SoupSize, "soup size")
+	flags.StringVar(&from_flags.SoupSymmetry, "symmetry", from_flags.SoupSymmetry, strings.Join(symmetry_names, ", "))
+	flags.BoolVar(&from_flags.StartSoup, "soup", from_flags.StartSoup, "start with the soup of -seed in the center")
+	flags.Int64Var(&seed, "seed", 0, "seed for all random numbers (default: current time)")
+	flags.StringVar(&from_flags.Pattern, "pattern", from_flags.Pattern, "RLE or .cells pattern to start with")
+	flags.IntVar(&from_flags.Speed, "speed", from_flags.Speed, fmt.Sprintf("starting speed level 0..%d", len(speed_levels)-1))
+	flags.StringVar(&from_flags.Theme, "theme", from_flags.Theme, strings.Join(theme_names(), ", "))
+	flags.StringVar(&from_flags.Keymap, "keymap", from_flags.Keymap, "JSON keymap file")
+	flags.BoolVar(&from_flags.AutoPause, "autopause", from_flags.AutoPause, "pause when the board stabilizes")
+	flags.BoolVar(&from_flags.RemoveEscapes, "remove-escapes", from_flags.RemoveEscapes, "delete gliders and other spaceships leaving the pattern")
+	flags.IntVar(&from_flags.CensusDistance, "census-distance", from_flags.CensusDistance, "cells this close belong to one object in the census")
+	flags.IntVar(&from_flags.Autosave, "autosave", from_flags.Autosave, "autosave interval in seconds, 0 disables")
+	flags.StringVar(&from_flags.AnimationFile, "animation-file", from_flags.AnimationFile, "animation file, .gif or .png/.apng")
+	flags.IntVar(&from_flags.AnimationFrom, "animation-from", from_flags.AnimationFrom, "first animation frame, generations after the current one")
+	flags.IntVar(&from_flags.AnimationTo, "animation-to", from_flags.AnimationTo, "last animation frame, generations after the current one")
+	flags.IntVar(&from_flags.AnimationCell, "animation-cell", from_flags.AnimationCell, "animation cell size in pixels, 0: as on screen")
+	flags.IntVar(&from_flags.AnimationDelay, "animation-delay", from_flags.AnimationDelay, "animation frame delay in milliseconds")
+	flags.StringVar(&from_flags.AnimationTheme, "animation-theme", from_flags.AnimationTheme, "animation theme, default: as on screen")
+	flags.BoolVar(&from_flags.AnimationGrid, "animation-grid", from_flags.AnimationGrid, "draw grid lines in the animation")
+	flags.StringVar(&from_flags.AnimationCrop, "animation-crop", from_flags.AnimationCrop, "animation region: all, or x,y,w,h in board cells (default: visible part)")
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	// файл по умолчанию необязателен, а явно указанный должен существовать
+	var config_set = false
+	flags.Visit(func(f *flag.Flag) {
+		config_set = config_set || f.Name == "config"
+	})
+	if err := load_config(*config_file, &cfg); err != nil && (config_set || !errors.Is(err, os.ErrNotExist)) {
+		return cfg, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "width":
+			cfg.WindowWidth = from_flags.WindowWidth
+		case "height":
+			cfg.WindowHeight = from_flags.WindowHeight
+		case "cell":
+			cfg.CellSize = from_flags.CellSize
+		case "rule":
+			cfg.Rule = from_flags.Rule
+		case "topology":
+			cfg.Topology = from_flags.Topology
+		case "density":
+			cfg.SoupDensity = from_flags.SoupDensity
+		case "soup-size":
+			cfg.SoupSize = from_flags.SoupSize
+		case "symmetry":
+			cfg.SoupSymmetry = from_flags.SoupSymmetry
+		case "soup":
+			cfg.StartSoup = from_flags.StartSoup
+		case "seed":
+			cfg.Seed = &seed
+		case "pattern":
+			cfg.Pattern = from_flags.Pattern
+		case "speed":
+			cfg.Speed = from_flags.Speed
+		case "theme":
+			cfg.Theme = from_flags.Theme
+		case "keymap":
+			cfg.Keymap = from_flags.Keymap
+		case "autopause":
+			cfg.AutoPause = from_flags.AutoPause
+		case "remove-escapes":
+			cfg.RemoveEscapes = from_flags.RemoveEscapes
+		case "census-distance":
+			cfg.CensusDistance = from_flags.CensusDistance
+		case "autosave":
+			cfg.Autosave = from_flags.Autosave
+		case "animation-file":
+			cfg.AnimationFile = from_flags.AnimationFile
+		case "animation-from":
+			cfg.AnimationFrom = from_flags.AnimationFrom
+		case "animation-to":
+			cfg.AnimationTo = from_flags.AnimationTo
+		case "animation-cell":
+			cfg.AnimationCell = from_flags.AnimationCell
+		case "animation-delay":
+			cfg.AnimationDelay = from_flags.AnimationDelay
+		case "animation-theme":
+			cfg.AnimationTheme = from_flags.AnimationTheme
+		case "animation-grid":
+			cfg.AnimationGrid = from_flags.AnimationGrid
+		case "animation-crop":
+			cfg.AnimationCrop = from_flags.AnimationCrop
+		}
+	})
+
+	if cfg.Seed == nil {
+		var now = time.Now().UnixNano()
+		cfg.Seed = &now
+	}
+	return cfg, cfg.validate()
+}
+
+// применяем глобальные настройки: размеры, правило, топологию и тему
+func apply_config(cfg app_config) {
+	set_layout(cfg.WindowWidth, cfg.WindowHeight, cfg.CellSize)
+	active_rule, _ = parse_rule(cfg.Rule)
+	active_topology, _ = parse_topology(cfg.Topology)
+	black = themes[cfg.Theme].alive
+	white = themes[cfg.Theme].background
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	// дольше не ищем период излучателя
+	emitter_max_gens = 2000
+	// самый длинный период паффера, который ищем
+	emitter_max_period = 300
+	// паффер должен повторяться хотя бы столько поколений подряд, чтобы не принять за него случайное совпадение
+	emitter_min_window = 120
+	// после того как нашли период, столько периодов считаем вылетевшие корабли
+	emitter_periods = 4
+)
+
+// что за излучатель
+const (
+	emitter_none    = iota // ничего не выпускает: умер, застыл, осциллирует или летит
+	emitter_gun            // стоит на месте и выпускает корабли
+	emitter_rake           // летит и выпускает корабли
+	emitter_puffer         // летит и оставляет за собой мусор
+	emitter_unknown        // период не нашли
+)
+
+// итог анализа: период того, что остается после удаления улетевших кораблей, и сколько кораблей вылетает за период
+type emitter_report struct {
+	kind        int
+	result      period_result // для паффера - период и сдвиг его головы
+	emitted     map[escape_ship]int
+	generations int
+}
+
+// строки отчета для CLI и окна
+func (r emitter_report) lines() []string {
+	var lines = []string{}
+	switch r.kind {
+	case emitter_none:
+		lines = append(lines, fmt.Sprintf("not an emitter: %s", r.result))
+	case emitter_gun:
+		lines = append(lines, fmt.Sprintf("gun period %d", r.result.period))
+	case emitter_rake:
+		lines = append(lines, fmt.Sprintf("rake period %d moving (%d,%d)", r.result.period, r.result.dx, r.result.dy))
+	case emitter_puffer:
+		lines = append(lines, fmt.Sprintf("puffer period %d moving (%d,%d)", r.result.period, r.result.dx, r.result.dy))
+	default:
+		return append(lines, fmt.Sprintf("no period found in %d generations", r.generations))
+	}
+
+	var ships = []escape_ship{}
+	for ship := range r.emitted {
+		ships = append(ships, ship)
+	}
+	sort.Slice(ships, func(i, j int) bool {
+		if ships[i].name != ships[j].name {
+			return ships[i].name < ships[j].name
+		}
+		return ships[i].direction < ships[j].direction
+	})
+	for _, ship := range ships {
+		var count = r.emitted[ship]
+		lines = append(lines, fmt.Sprintf("emits %d %s %s every %d generations (%.4f per generation)",
+			count, ship.name, ship.direction, r.result.period, float64(count)/float64(r.result.period)))
+	}
+	return lines
+}
+
+// популяция и прямоугольник в абсолютных координатах на одном поколении
+type emitter_sample struct {
+	population int
+	min_x      int
+	min_y      int
+	max_x      int
+	max_y      int
+}
+
+func (s emitter_sample) minus(other emitter_sample) emitter_sample {
+	return emitter_sample{s.population - other.population, s.min_x - other.min_x, s.min_y - other.min_y, s.max_x - other.max_x, s.max_y - other.max_y}
+}
+
+func sample_universe(u *universe) emitter_sample {
+	var box = u.box()
+	return emitter_sample{u.population(), box.min_x - u.origin.x, box.min_y - u.origin.y, box.max_x - u.origin.x, box.max_y - u.origin.y}
+}
+
+// паффер: за период популяция и края прямоугольника каждый раз меняются одинаково и хотя бы один край сдвигается;
+// сдвиг головы - это сдвиг края, который уходит вперед, хвост из мусора остается на месте
+func find_puffer(samples []emitter_sample) (period_result, bool) {
+	var n = len(samples) - 1
+	for p := 1; p <= emitter_max_period; p++ {
+		var window = max(emitter_periods*p, emitter_min_window)
+		if n-window-p < 0 {
+			break
+		}
+		var delta = samples[n].minus(samples[n-p])
+		if delta.min_x == 0 && delta.max_x == 0 && delta.min_y == 0 && delta.max_y == 0 {
+			continue
+		}
+		var same = true
+		for i := 1; i <= window && same; i++ {
+			same = samples[n-i].minus(samples[n-i-p]) == delta
+		}
+		if !same {
+			continue
+		}
+		var result = period_result{kind: period_spaceship, period: p, dx: delta.max_x, dy: delta.max_y}
+		if result.dx == 0 {
+			result.dx = delta.min_x
+		}
+		if result.dy == 0 {
+			result.dy = delta.min_y
+		}
+		return result, true
+	}
+	return period_result{}, false
+}
+
+// считаем шаблон, стирая улетающие корабли, пока то, что остается, не повторится;
+// потом еще emitter_periods периодов считаем, сколько каких кораблей вылетает
+func analyze_emitter(p pattern, max_gens int) emitter_report {
+	// излучатель считаем на бесконечной плоскости, даже если поле - тор
+	var u = new_plane_universe(p)
+	// корабли ищем каждое поколение, тогда они стираются в одной и той же фазе и период не удлиняется
+	var escapes = new_escape_detector(true)
+	var detector = new_period_detector(stability_window)
+	var samples = []emitter_sample{sample_universe(u)}
+	var report = emitter_report{kind: emitter_unknown, emitted: map[escape_ship]int{}}
+	var result = u.observe(detector)
+	var puffer = false
+	for u.generation < max_gens && !result.stable() && !puffer {
+		u.step()
+		u.observe_escapes(escapes)
+		result = u.observe(detector)
+		samples = append(samples, sample_universe(u))
+		if !result.stable() {
+			result, puffer = find_puffer(samples)
+		}
+	}
+	report.generations = u.generation
+	if !result.stable() {
+		return report
+	}
+	report.result = result
+	if result.kind == period_died {
+		report.kind = emitter_none
+		return report
+	}
+
+	// период нашли, теперь считаем корабли за несколько целых периодов
+	var end = u.generation + emitter_periods*result.period
+	for u.generation < end {
+		u.step()
+		for _, find := range u.observe_escapes(escapes) {
+			report.emitted[find.ship]++
+		}
+	}
+	report.generations = u.generation
+	// если корабли вылетают неровно, округляем вверх, чтобы редкие не потерялись
+	for ship, count := range report.emitted {
+		report.emitted[ship] = (count + emitter_periods - 1) / emitter_periods
+	}
+
+	switch {
+	case puffer:
+		report.kind = emitter_puffer
+	case len(report.emitted) == 0:
+		report.kind = emitter_none
+	case result.kind == period_spaceship:
+		report.kind = emitter_rake
+	default:
+		report.kind = emitter_gun
+	}
+	return report
+}
+
+// life emitter: период ружья или паффера, что и куда оно выпускает и как часто
+func emitter_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life emitter", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var gens = flags.Int("gens", emitter_max_gens, "give up after this many generations")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life emitter:", err)
+		return exit_usage
+	}
+
+	var report = analyze_emitter(start, *gens)
+	for _, line := range report.lines() {
+		fmt.Fprintln(stdout, line)
+	}
+	return exit_ok
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// клетки на таком расстоянии и ближе считаем одним объектом, когда ищем корабли:
+	// дальше двух клеток корабль ни с чем не взаимодействует в следующем поколении
+	escape_distance = 2
+	// столько пустых клеток должно остаться между кораблем и основной массой
+	escape_gap = 4
+	// корабли ищем не каждое поколение, глайдер за это время сдвигается на две клетки
+	escape_check_interval = 8
+)
+
+// стандартные корабли, которые узнаем; формы берем из object_catalog
+var escape_ship_names = []string{"glider", "LWSS", "MWSS", "HWSS"}
+
+// стороны света в порядке вывода: x растет на восток, y - на юг, как на экране
+var escape_directions = []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+func direction_name(dx int, dy int) string {
+	var name = ""
+	if dy < 0 {
+		name += "N"
+	} else if dy > 0 {
+		name += "S"
+	}
+	if dx > 0 {
+		name += "E"
+	} else if dx < 0 {
+		name += "W"
+	}
+	return name
+}
+
+// корабль в одной из ориентаций: имя, направление и сдвиг за период
+type escape_ship struct {
+	name      string
+	direction string
+	dx        int
+	dy        int
+	period    int
+}
+
+// корабль на поле, клетки и прямоугольник - в абсолютных координатах
+type escape_find struct {
+	ship       escape_ship
+	cells      []POS
+	box        bounding_box
+	generation int
+}
+
+// корабль улетает, если он целиком за основной массой в ту сторону, куда летит, и не ближе escape_gap
+func (f escape_find) leaving(rest bounding_box) bool {
+	return (f.ship.dx > 0 && f.box.min_x > rest.max_x+escape_gap) ||
+		(f.ship.dx < 0 && f.box.max_x < rest.min_x-escape_gap) ||
+		(f.ship.dy > 0 && f.box.min_y > rest.max_y+escape_gap) ||
+		(f.ship.dy < 0 && f.box.max_y < rest.min_y-escape_gap)
+}
+
+// тот же корабль, что и при прошлой проверке: форма та же и он там, куда должен был долететь;
+// внутри периода корабль может отставать от расчетного места на пару клеток
+func (f escape_find) same_as(previous escape_find) bool {
+	if f.ship != previous.ship {
+		return false
+	}
+	var elapsed = f.generation - previous.generation
+	var x = previous.box.min_x + f.ship.dx*elapsed/f.ship.period
+	var y = previous.box.min_y + f.ship.dy*elapsed/f.ship.period
+	return abs(f.box.min_x-x) <= escape_distance && abs(f.box.min_y-y) <= escape_distance
+}
+
+// прямоугольник вокруг клеток
+func cells_box(cells []POS) bounding_box {
+	var box = bounding_box{empty: true}
+	for _, c := range cells {
+		box = box.extend(bounding_box{c.x, c.y, c.x, c.y, false})
+	}
+	return box
+}
+
+// прямоугольник, в который помещаются оба
+func (b bounding_box) extend(other bounding_box) bounding_box {
+	if b.empty {
+		return other
+	}
+	if other.empty {
+		return b
+	}
+	return bounding_box{min(b.min_x, other.min_x), min(b.min_y, other.min_y), max(b.max_x, other.max_x), max(b.max_y, other.max_y), false}
+}
+
+// формы всех фаз и ориентаций кораблей зависят от правила, считаем их для каждого правила отдельно
+var (
+	escape_ship_cache = map[string]map[string]escape_ship{}
+	escape_ship_mutex sync.Mutex
+)
+
+func escape_ship_shapes() map[string]escape_ship {
+	escape_ship_mutex.Lock()
+	defer escape_ship_mutex.Unlock()
+	var rule = active_rule.String()
+	if shapes, ok := escape_ship_cache[rule]; ok {
+		return shapes
+	}
+	var shapes = map[string]escape_ship{}
+	escape_ship_cache[rule] = shapes
+	for _, name := range escape_ship_names {
+		p, err := parse_rle(strings.NewReader("x = 0, y = 0\n" + object_catalog[name]))
+		if err != nil {
+			continue
+		}
+		for _, transform := range object_transforms {
+			var moved = make([]POS, len(p.cells))
+			for i, c := range p.cells {
+				moved[i] = transform(c)
+			}
+			// при другом правиле корабль может и не лететь
+			var phases, result = object_phases(moved)
+			if result.kind != period_spaceship {
+				continue
+			}
+			var ship = escape_ship{name, direction_name(result.dx, result.dy), result.dx, result.dy, result.period}
+			for _, phase := range phases {
+				shapes[cells_key(normalize_cells(phase))] = ship
+			}
+		}
+	}
+	return shapes
+}
+
+// ищем корабли, которые улетают от основной массы, и считаем каждый один раз
+type escape_detector struct {
+	// стирать улетевшие корабли, чтобы поле не росло бесконечно
+	remove bool
+	counts map[escape_ship]int
+	// посчитанные, но не стертые корабли, чтобы не считать их снова
+	tracked []escape_find
+}
+
+func new_escape_detector(remove bool) *escape_detector {
+	return &escape_detector{remove: remove, counts: map[escape_ship]int{}}
+}
+
+// забываем посчитанные корабли, например когда поле очистили
+func (d *escape_detector) reset() {
+	d.counts = map[escape_ship]int{}
+	d.tracked = nil
+}
+
+// живые клетки field в абсолютных координатах, origin - как в universe
+func field_cells(height int, width int, field [][]byte, origin POS) []POS {
+	var cells = []POS{}
+	for x := 0; x < height; x++ {
+		for y := 0; y < width; y++ {
+			if field[x][y] == 1 {
+				cells = append(cells, POS{x - origin.x, y - origin.y})
+			}
+		}
+	}
+	return cells
+}
+
+// клетки без кораблей, которые летят отдельно от всего остального
+func without_ships(cells []POS) []POS {
+	var shapes = escape_ship_shapes()
+	var rest = []POS{}
+	for _, object := range find_objects(cells, escape_distance) {
+		if _, ok := shapes[cells_key(normalize_cells(object))]; !ok {
+			rest = append(rest, object...)
+		}
+	}
+	return rest
+}
+
+// проверяем поколение, origin - сдвиг field относительно абсолютных координат, как в universe;
+// возвращаем корабли, которые улетели с прошлой проверки, при remove они уже стерты с field
+func (d *escape_detector) observe(height int, width int, field [][]byte, origin POS, generation int) []escape_find {
+	var cells = field_cells(height, width, field, origin)
+
+	// основная масса - все, что не корабли; без нее улетать не от чего
+	var shapes = escape_ship_shapes()
+	var ships = []escape_find{}
+	var rest = bounding_box{empty: true}
+	for _, object := range find_objects(cells, escape_distance) {
+		var box = cells_box(object)
+		if ship, ok := shapes[cells_key(normalize_cells(object))]; ok {
+			ships = append(ships, escape_find{ship, object, box, generation})
+		} else {
+			rest = rest.extend(box)
+		}
+	}
+	if rest.empty {
+		d.tracked = nil
+		return nil
+	}
+
+	var escaped = []escape_find{}
+	var tracked = []escape_find{}
+	for _, find := range ships {
+		if !find.leaving(rest) {
+			continue
+		}
+		if d.is_tracked(find) {
+			tracked = append(tracked, find)
+			continue
+		}
+		d.counts[find.ship]++
+		escaped = append(escaped, find)
+		if !d.remove {
+			tracked = append(tracked, find)
+			continue
+		}
+		for _, c := range find.cells {
+			field[c.x+origin.x][c.y+origin.y] = 0
+		}
+	}
+	d.tracked = tracked
+	return escaped
+}
+
+func (d *escape_detector) is_tracked(find escape_find) bool {
+	for _, previous := range d.tracked {
+		if find.same_as(previous) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *escape_detector) total() int {
+	var total = 0
+	for _, count := range d.counts {
+		total += count
+	}
+	return total
+}
+
+// сколько кораблей улетело в каждую сторону и сколько каких: "NE 3, W 1 (glider 3, LWSS 1)"
+func (d *escape_detector) String() string {
+	if len(d.counts) == 0 {
+		return "none"
+	}
+	var by_direction = map[string]int{}
+	var by_name = map[string]int{}
+	for ship, count := range d.counts {
+		by_direction[ship.direction] += count
+		by_name[ship.name] += count
+	}
+	var directions = []string{}
+	for _, direction := range escape_directions {
+		if by_direction[direction] > 0 {
+			directions = append(directions, fmt.Sprintf("%s %d", direction, by_direction[direction]))
+		}
+	}
+	var names = []string{}
+	for _, name := range escape_ship_names {
+		if by_name[name] > 0 {
+			names = append(names, fmt.Sprintf("%s %d", name, by_name[name]))
+		}
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(directions, ", "), strings.Join(names, ", "))
+}
@@ -0,0 +1,129 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// куда пишем анимацию из окна и сколько поколений в нее берем по умолчанию
+const (
+	animation_file   = "life.gif"
+	animation_frames = 100
+	animation_delay  = 100
+	snapshot_file    = "life.png"
+)
+
+// копия текущего поля для расчетов без окна, абсолютные координаты совпадают с индексами field
+func (g *MyGame) board_universe() *universe {
+	return &universe{height: g.height, width: g.width, field: copy_field(g.field), generation: g.stats.generation, topology: active_topology}
+}
+
+// видимая часть поля в координатах field
+func (g *MyGame) viewport() bounding_box {
+	return bounding_box{g.x_offset, g.y_offset, g.x_offset + gameHeight - 1, g.y_offset + gameWidth - 1, false}
+}
+
+// анимация из окна: те же настройки, что у life animate, но from и to - поколения после текущего
+type window_animation struct {
+	file     string
+	settings animation_settings
+	// пустая область в конфиге: пишем видимую часть поля
+	view bool
+}
+
+// настройки анимации из конфига, ошибки - как у life animate
+func (cfg app_config) window_animation() (window_animation, error) {
+	var a = window_animation{
+		file: cfg.AnimationFile,
+		settings: animation_settings{
+			from:  cfg.AnimationFrom,
+			to:    cfg.AnimationTo,
+			cell:  cfg.AnimationCell,
+			delay: cfg.AnimationDelay,
+			theme: themes[cfg.Theme],
+			grid:  cfg.AnimationGrid,
+		},
+	}
+	if a.settings.cell == 0 {
+		a.settings.cell = cfg.CellSize
+	}
+	if cfg.AnimationTheme != "" {
+		var ok bool
+		if a.settings.theme, ok = themes[cfg.AnimationTheme]; !ok {
+			return a, fmt.Errorf("theme %q: expected one of %s", cfg.AnimationTheme, strings.Join(theme_names(), ", "))
+		}
+	}
+	switch cfg.AnimationCrop {
+	case "":
+		a.view = true
+	case "all":
+		a.settings.crop = bounding_box{empty: true}
+	default:
+		var err error
+		if a.settings.crop, err = parse_crop(cfg.AnimationCrop); err != nil {
+			return a, err
+		}
+	}
+	switch strings.ToLower(filepath.Ext(a.file)) {
+	case ".gif", ".png", ".apng":
+	default:
+		return a, fmt.Errorf("%s: expected .gif, .png or .apng", a.file)
+	}
+	return a, a.settings.validate()
+}
+
+// записываем следующие поколения в GIF или APNG в фоне, само поле не меняется
+func (g *MyGame) export_animation() {
+	var a = g.animation
+	a.settings.from += g.stats.generation
+	a.settings.to += g.stats.generation
+	if a.view {
+		a.settings.crop = g.viewport()
+	}
+	var u = g.board_universe()
+	go func() {
+		var frames = record_animation(u, a.settings)
+		if err := save_animation(a.file, frames, a.settings.delay); err != nil {
+			log.Println(err)
+			return
+		}
+		log.Printf("%d frames saved to %s", len(frames), a.file)
+	}()
+}
+
+// снимок всех живых клеток, одна клетка - один черный пиксель, такой файл можно загрузить как шаблон
+func (g *MyGame) export_board_png(filename string) error {
+	var u = g.board_universe()
+	if err := save_snapshot(filename, u.cells(), u.box(), 1, themes["mono"]); err != nil {
+		return err
+	}
+	log.Printf("board saved to %s", filename)
+	return nil
+}
+
+// снимок выделения, как у export_board_png: один пиксель на клетку, пустые края выделения тоже пишем
+func (g *MyGame) export_selection_png(filename string) error {
+	var box = g.selection_box()
+	if box.empty {
+		return fmt.Errorf("%s: nothing selected, drag a rectangle with the Select tool", filename)
+	}
+	if err := save_snapshot(filename, g.selection_cells(), box, 1, themes["mono"]); err != nil {
+		return err
+	}
+	log.Printf("selection saved to %s", filename)
+	return nil
+}
+
+// снимок видимой части поля в масштабе и цветах экрана
+func (g *MyGame) export_view_png(filename string) error {
+	var u = g.board_universe()
+	if err := save_snapshot(filename, u.cells(), g.viewport(), scale, theme{black, white}); err != nil {
+		return err
+	}
+	log.Printf("view saved to %s", filename)
+	return nil
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// файл, в который после каждой ширины записываем, что уже перебрали
+	find_checkpoint_file = "find.checkpoint"
+	// каталог шаблонов, куда сохраняем находки
+	find_catalog_dir = "patterns"
+	// сколько разных шаблонов ищем на одной ширине
+	find_per_width = 16
+	// сколько по умолчанию ищем на одной ширине, не уложились - ширина переберется снова при продолжении
+	find_timeout = time.Minute
+)
+
+// что ищем: шаблон с периодом Period, который за период сдвигается на (DX, DY), без сдвига - осциллятор;
+// все фазы вместе не шире Width и, не считая сдвига, не длиннее Length
+type find_target struct {
+	Rule   string `json:"rule"`
+	Period int    `json:"period"`
+	DX     int    `json:"dx"`
+	DY     int    `json:"dy"`
+	Width  int    `json:"width"`
+	Length int    `json:"length"`
+}
+
+// сохраненный ход поиска: перебранные ширины и apgcode найденного
+type find_checkpoint struct {
+	Target find_target `json:"target"`
+	Done   []int       `json:"done"`
+	Found  []string    `json:"found"`
+}
+
+// скорость как в gfind: c/4, 2c/5 - на север вдоль оси, c/4d - на северо-восток по диагонали
+func parse_velocity(text string) (dx int, dy int, period int, err error) {
+	var s = strings.ToLower(strings.TrimSpace(text))
+	var diagonal = strings.HasSuffix(s, "d")
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "d"), "o")
+	speed, denominator, ok := strings.Cut(s, "c/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("velocity %q: expected c/P, kc/P or c/Pd", text)
+	}
+	var k = 1
+	if speed != "" {
+		if k, err = strconv.Atoi(speed); err != nil || k < 1 {
+			return 0, 0, 0, fmt.Errorf("velocity %q: bad speed %q", text, speed)
+		}
+	}
+	if period, err = strconv.Atoi(denominator); err != nil || period < 1 {
+		return 0, 0, 0, fmt.Errorf("velocity %q: bad period %q", text, denominator)
+	}
+	// быстрее c/2 вдоль оси и c/4 по диагонали корабль на пустом поле лететь не может
+	if diagonal {
+		if 4*k > period {
+			return 0, 0, 0, fmt.Errorf("velocity %q: diagonal ships are at most c/4", text)
+		}
+		return k, -k, period, nil
+	}
+	if 2*k > period {
+		return 0, 0, 0, fmt.Errorf("velocity %q: orthogonal ships are at most c/2", text)
+	}
+	return 0, -k, period, nil
+}
+
+// делители периода, на которые шаблон не должен повторяться: period/q для каждого простого q
+func find_subperiods(period int) []int {
+	var subperiods = []int{}
+	var n = period
+	for q := 2; q <= n; q++ {
+		if n%q != 0 {
+			continue
+		}
+		subperiods = append(subperiods, period/q)
+		for n%q == 0 {
+			n /= q
+		}
+	}
+	return subperiods
+}
+
+// задача для одной ширины: переменные клеток всех поколений в прямоугольнике box, вне его клетки мертвы.
+// Прямоугольник - это полоса, по которой шаблон проходит за период; вместе все фазы касаются левого,
+// правого и верхнего края, так ширина ровно width и сдвигов одного шаблона нет.
+// Поколение period - это нулевое, сдвинутое на (dx, dy), поэтому отдельных переменных у него нет
+type find_problem struct {
+	target find_target
+	box    bounding_box
+	solver *sat_solver
+}
+
+func (p *find_problem) inside(x int, y int) bool {
+	return x >= p.box.min_x && x <= p.box.max_x && y >= p.box.min_y && y <= p.box.max_y
+}
+
+func (p *find_problem) variable(t int, x int, y int) int {
+	if t == p.target.Period {
+		return p.variable(0, x-p.target.DX, y-p.target.DY)
+	}
+	// клетка нулевого поколения после сдвига тоже должна остаться в полосе
+	if !p.inside(x, y) || (t == 0 && !p.inside(x+p.target.DX, y+p.target.DY)) {
+		return 0
+	}
+	return 1 + t*p.box.size_x()*p.box.size_y() + (x-p.box.min_x)*p.box.size_y() + (y - p.box.min_y)
+}
+
+func new_find_problem(target find_target, width int, rule life_rule) *find_problem {
+	var p = &find_problem{target: target}
+	p.box = bounding_box{0, 0, width + abs(target.DX) - 1, target.Length + abs(target.DY) - 1, false}
+	p.solver = new_sat_solver(target.Period * p.box.size_x() * p.box.size_y())
+
+	for t := 0; t < target.Period; t++ {
+		for x := p.box.min_x - 1; x <= p.box.max_x+1; x++ {
+			for y := p.box.min_y - 1; y <= p.box.max_y+1; y++ {
+				var neighbours = []int{}
+				for dx := -1; dx <= 1; dx++ {
+					for dy := -1; dy <= 1; dy++ {
+						if dx != 0 || dy != 0 {
+							neighbours = append(neighbours, p.variable(t, x+dx, y+dy))
+						}
+					}
+				}
+				encode_life_cell(p.solver, p.variable(t, x, y), neighbours, p.variable(t+1, x, y), false, rule)
+			}
+		}
+	}
+
+	var left, right, top = []int{}, []int{}, []int{}
+	for t := 0; t < target.Period; t++ {
+		for y := p.box.min_y; y <= p.box.max_y; y++ {
+			left = append(left, p.variable(t, p.box.min_x, y))
+			right = append(right, p.variable(t, p.box.max_x, y))
+		}
+		for x := p.box.min_x; x <= p.box.max_x; x++ {
+			top = append(top, p.variable(t, x, p.box.min_y))
+		}
+	}
+	p.solver.add_clause(left)
+	p.solver.add_clause(right)
+	p.solver.add_clause(top)
+
+	// период ровно period: через period/q поколений шаблон должен отличаться от сдвинутого нулевого
+	for _, r := range find_subperiods(target.Period) {
+		if (target.DX*r)%target.Period != 0 || (target.DY*r)%target.Period != 0 {
+			continue
+		}
+		var sx, sy = target.DX * r / target.Period, target.DY * r / target.Period
+		var differs = []int{}
+		for x := p.box.min_x; x <= p.box.max_x; x++ {
+			for y := p.box.min_y; y <= p.box.max_y; y++ {
+				var a, b = p.variable(r, x, y), p.variable(0, x-sx, y-sy)
+				if a == 0 && b == 0 {
+					continue
+				}
+				// d - клетка в двух поколениях разная
+				var d = p.solver.new_var()
+				differs = append(differs, d)
+				switch {
+				case a == 0:
+					p.solver.add_clause([]int{-d, b})
+				case b == 0:
+					p.solver.add_clause([]int{-d, a})
+				default:
+					p.solver.add_clause([]int{-d, a, b})
+					p.solver.add_clause([]int{-d, -a, -b})
+				}
+			}
+		}
+		p.solver.add_clause(differs)
+	}
+	return p
+}
+
+// клетки поколения t из найденного набора
+func (p *find_problem) cells(model []bool, t int) []POS {
+	var cells = []POS{}
+	for x := p.box.min_x; x <= p.box.max_x; x++ {
+		for y := p.box.min_y; y <= p.box.max_y; y++ {
+			if v := p.variable(t, x, y); v != 0 && model[v] {
+				cells = append(cells, POS{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// запрещаем найденный шаблон в нулевом поколении во всех фазах, отражениях и местах полосы
+func (p *find_problem) block(model []bool) {
+	for t := 0; t < p.target.Period; t++ {
+		var phase = p.cells(model, t)
+		for _, transform := range object_transforms {
+			var moved = make([]POS, len(phase))
+			for i, c := range phase {
+				moved[i] = transform(c)
+			}
+			moved = normalize_cells(moved)
+			var size = cells_box(moved)
+			for ox := p.box.min_x; ox+size.max_x <= p.box.max_x; ox++ {
+				for oy := p.box.min_y; oy+size.max_y <= p.box.max_y; oy++ {
+					p.block_at(moved, ox, oy)
+				}
+			}
+		}
+	}
+}
+
+func (p *find_problem) block_at(cells []POS, ox int, oy int) {
+	var alive = map[POS]bool{}
+	for _, c := range cells {
+		if p.variable(0, c.x+ox, c.y+oy) == 0 {
+			return
+		}
+		alive[POS{c.x + ox, c.y + oy}] = true
+	}
+	var clause = []int{}
+	for x := p.box.min_x; x <= p.box.max_x; x++ {
+		for y := p.box.min_y; y <= p.box.max_y; y++ {
+			var v = p.variable(0, x, y)
+			switch {
+			case v == 0:
+			case alive[POS{x, y}]:
+				clause = append(clause, -v)
+			default:
+				clause = append(clause, v)
+			}
+		}
+	}
+	p.solver.add_clause(clause)
+}
+
+// итог одной ширины; если время вышло, err - errSatTimeout, а найденное до того - в found
+type find_job struct {
+	width int
+	found [][]POS
+	err   error
+}
+
+// ищем до count разных шаблонов шириной ровно width; несколько отдельных кораблей или осцилляторов,
+// которые друг на друга не влияют, за находку не считаем
+func find_width(target find_target, width int, count int, timeout time.Duration, rule life_rule) find_job {
+	var job = find_job{width: width}
+	var problem = new_find_problem(target, width, rule)
+	problem.solver.deadline = time.Now().Add(timeout)
+	for len(job.found) < count {
+		found, err := problem.solver.solve()
+		if err != nil {
+			job.err = err
+			return job
+		}
+		if !found {
+			break
+		}
+		var model = problem.solver.model()
+		var cells = problem.cells(model, 0)
+		problem.block(model)
+		if len(merge_interacting(find_objects(cells, census_distance))) == 1 {
+			job.found = append(job.found, cells)
+		}
+	}
+	return job
+}
+
+// перебираем ширины в нескольких горутинах, пропуская done; итоги приходят по мере готовности
+func run_find(target find_target, done map[int]bool, workers int, count int, timeout time.Duration, rule life_rule) <-chan find_job {
+	var widths = make(chan int)
+	var jobs = make(chan find_job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for width := range widths {
+				jobs <- find_width(target, width, count, timeout, rule)
+			}
+		}()
+	}
+	go func() {
+		for width := 1; width <= target.Width; width++ {
+			if !done[width] {
+				widths <- width
+			}
+		}
+		close(widths)
+		wg.Wait()
+		close(jobs)
+	}()
+	return jobs
+}
+
+func load_find_checkpoint(filename string) (find_checkpoint, error) {
+	var checkpoint = find_checkpoint{}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return checkpoint, err
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("read %s: %w", filename, err)
+	}
+	return checkpoint, nil
+}
+
+// пишем во временный файл и переименовываем, чтобы остановка посреди записи не портила checkpoint
+func save_find_checkpoint(filename string, checkpoint find_checkpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	var temp = filename + ".tmp"
+	if err := os.WriteFile(temp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp, filename)
+}
+
+// проверяем находку обычным счетом и сохраняем в каталог как <apgcode>.rle; пустая строка - такой файл уже есть
+func save_find(dir string, cells []POS, target find_target, velocity string) (string, error) {
+	var phases, result = pattern_phases(cells, target.Period)
+	var moving = target.DX != 0 || target.DY != 0
+	if !result.stable() || result.period != target.Period || (result.kind == period_spaceship) != moving {
+		return "", fmt.Errorf("pattern %v does not repeat as expected: %s", cells, result)
+	}
+	var code = apgcode(phases, result)
+	var filename = filepath.Join(dir, code+".rle")
+	if _, err := os.Stat(filename); err == nil {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(file, "#N %s\n", code)
+	if name := classify_object(cells).name; name != "" {
+		fmt.Fprintf(file, "#C %s\n", name)
+	}
+	fmt.Fprintf(file, "#C %s, found by life find -velocity %s\n", result, velocity)
+	if err := write_rle(file, pattern_from_cells(cells), active_rule); err != nil {
+		file.Close()
+		return "", fmt.Errorf("write %s: %w", filename, err)
+	}
+	return filename, file.Close()
+}
+
+// life find: ищем корабли и осцилляторы с заданными правилом, периодом и скоростью в полосе ограниченной ширины
+func find_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life find", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var rule_text = flags.String("rule", "B3/S23", "rule in B/S notation")
+	var velocity = flags.String("velocity", "0", "ship velocity: c/4, 2c/5, c/4d; 0 searches oscillators")
+	var period = flags.Int("period", 0, "oscillator period, for ships the period comes from the velocity")
+	var width = flags.Int("width", 6, "largest width of the pattern")
+	var length = flags.Int("length", 12, "largest length of the pattern along its movement")
+	var workers = flags.Int("workers", runtime.NumCPU(), "widths searched in parallel")
+	var count = flags.Int("count", find_per_width, "patterns to find for each width")
+	var timeout = flags.Duration("timeout", find_timeout, "time limit for each width")
+	var dir = flags.String("dir", find_catalog_dir, "pattern catalog directory for the results")
+	var checkpoint_file = flags.String("checkpoint", find_checkpoint_file, "file to save progress to and resume from")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	rule, err := parse_rule(*rule_text)
+	if err != nil {
+		fmt.Fprintln(stderr, "life find:", err)
+		return exit_usage
+	}
+	// при B0 оживает все пустое поле, в ограниченной полосе так искать нельзя
+	if rule.birth[0] {
+		fmt.Fprintln(stderr, "life find: rules with B0 are not supported")
+		return exit_usage
+	}
+	var target = find_target{Rule: rule.String(), Width: *width, Length: *length}
+	if *velocity == "0" {
+		target.Period = *period
+	} else {
+		target.DX, target.DY, target.Period, err = parse_velocity(*velocity)
+		if err != nil {
+			fmt.Fprintln(stderr, "life find:", err)
+			return exit_usage
+		}
+		if *period != 0 && *period != target.Period {
+			fmt.Fprintf(stderr, "life find: velocity %s has period %d, not %d\n", *velocity, target.Period, *period)
+			return exit_usage
+		}
+	}
+	if target.Period < 1 || *width < 1 || *length < 1 || *workers < 1 || *count < 1 {
+		fmt.Fprintln(stderr, "life find: period, width, length, workers and count must be positive")
+		return exit_usage
+	}
+	active_rule = rule
+	active_topology = topology_plane
+
+	// продолжаем с того места, где остановились, если ищем то же самое
+	var checkpoint = find_checkpoint{Target: target, Done: []int{}, Found: []string{}}
+	if saved, err := load_find_checkpoint(*checkpoint_file); err == nil {
+		if saved.Target != target {
+			fmt.Fprintf(stderr, "life find: %s belongs to another search, remove it or use -checkpoint\n", *checkpoint_file)
+			return exit_usage
+		}
+		checkpoint = saved
+		fmt.Fprintf(stdout, "resuming: %d of %d widths done, %d patterns found\n", len(checkpoint.Done), target.Width, len(checkpoint.Found))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(stderr, "life find:", err)
+		return exit_error
+	}
+	var done = map[int]bool{}
+	for _, w := range checkpoint.Done {
+		done[w] = true
+	}
+	var found = map[string]bool{}
+	for _, code := range checkpoint.Found {
+		found[code] = true
+	}
+
+	var failed = false
+	for job := range run_find(target, done, *workers, *count, *timeout, rule) {
+		var saved = 0
+		for _, cells := range job.found {
+			filename, err := save_find(*dir, cells, target, *velocity)
+			if err != nil {
+				fmt.Fprintln(stderr, "life find:", err)
+				failed = true
+				continue
+			}
+			if filename == "" {
+				continue
+			}
+			saved++
+			found[strings.TrimSuffix(filepath.Base(filename), ".rle")] = true
+			fmt.Fprintf(stdout, "width %d: saved %s\n", job.width, filename)
+		}
+		if errors.Is(job.err, errSatTimeout) {
+			fmt.Fprintf(stdout, "width %d: %s, %d new patterns, the width will be searched again on resume\n", job.width, job.err, saved)
+			continue
+		}
+		fmt.Fprintf(stdout, "width %d: done, %d new patterns\n", job.width, saved)
+		checkpoint.Done = append(checkpoint.Done, job.width)
+		sort.Ints(checkpoint.Done)
+		checkpoint.Found = checkpoint.Found[:0]
+		for code := range found {
+			checkpoint.Found = append(checkpoint.Found, code)
+		}
+		sort.Strings(checkpoint.Found)
+		if err := save_find_checkpoint(*checkpoint_file, checkpoint); err != nil {
+			fmt.Fprintln(stderr, "life find:", err)
+			return exit_error
+		}
+	}
+	fmt.Fprintf(stdout, "%d of %d widths done, %d patterns found\n", len(checkpoint.Done), target.Width, len(found))
+	if failed {
+		return exit_error
+	}
+	return exit_ok
+}
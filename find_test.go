@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVelocity(t *testing.T) {
+	var tests = []struct {
+		text           string
+		dx, dy, period int
+	}{
+		{"c/4", 0, -1, 4},
+		{"2c/4", 0, -2, 4},
+		{"c/3", 0, -1, 3},
+		{"c/4d", 1, -1, 4},
+		{"2c/8d", 2, -2, 8},
+	}
+	for _, test := range tests {
+		dx, dy, period, err := parse_velocity(test.text)
+		if err != nil || dx != test.dx || dy != test.dy || period != test.period {
+			t.Errorf("%s: (%d, %d) period %d, %v", test.text, dx, dy, period, err)
+		}
+	}
+	for _, text := range []string{"", "c", "c/0", "0c/4", "c/1", "2c/3", "c/3d", "c/x"} {
+		if _, _, _, err := parse_velocity(text); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
+
+func TestFindSubperiods(t *testing.T) {
+	var tests = map[int][]int{1: {}, 2: {1}, 4: {2}, 6: {3, 2}, 12: {6, 4}, 30: {15, 10, 6}}
+	for period, want := range tests {
+		if got := find_subperiods(period); !slices.Equal(got, want) {
+			t.Errorf("period %d: %v, want %v", period, got, want)
+		}
+	}
+}
+
+// осцилляторы периода 2 шириной 3: блинкер среди них, и каждый действительно с периодом 2
+func TestFindOscillators(t *testing.T) {
+	var target = find_target{Rule: "B3/S23", Period: 2, Width: 3, Length: 3}
+	var job = find_width(target, 3, find_per_width, time.Minute, conway_rule())
+	if job.err != nil {
+		t.Fatal(job.err)
+	}
+	var codes = []string{}
+	for _, cells := range job.found {
+		var phases, result = pattern_phases(cells, target.Period)
+		if result.kind != period_oscillator || result.period != 2 {
+			t.Errorf("%v: %s", cells, result)
+			continue
+		}
+		codes = append(codes, apgcode(phases, result))
+	}
+	if !slices.Contains(codes, "xp2_7") {
+		t.Errorf("found %v, no blinker", codes)
+	}
+}
+
+// life find -velocity c/4d -width 3 находит планер, повторный запуск продолжает с checkpoint
+func TestFindGlider(t *testing.T) {
+	var dir = t.TempDir()
+	var args = []string{"-velocity", "c/4d", "-width", "3", "-workers", "1",
+		"-dir", filepath.Join(dir, "patterns"), "-checkpoint", filepath.Join(dir, "find.checkpoint")}
+	var stdout, stderr bytes.Buffer
+	if code := find_command(args, &stdout, &stderr); code != exit_ok {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "patterns", "xq4_153.rle")); err != nil {
+		t.Fatalf("%v\n%s", err, stdout.String())
+	}
+	checkpoint, err := load_find_checkpoint(filepath.Join(dir, "find.checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(checkpoint.Done, []int{1, 2, 3}) || !slices.Equal(checkpoint.Found, []string{"xq4_153"}) {
+		t.Errorf("checkpoint: done %v, found %v", checkpoint.Done, checkpoint.Found)
+	}
+
+	stdout.Reset()
+	if code := find_command(args, &stdout, &stderr); code != exit_ok {
+		t.Fatalf("resume: exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "resuming: 3 of 3 widths done") {
+		t.Errorf("resume: %s", stdout.String())
+	}
+
+	// другой поиск с тем же checkpoint не продолжаем
+	args[1] = "c/4"
+	if code := find_command(args, &stdout, &stderr); code != exit_usage {
+		t.Errorf("another search: exit code %d", code)
+	}
+}
@@ -2,9 +2,462 @@ package main
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"log"
 	"math/rand"
+	"strings"
 )
 
+type POS struct {
+	x int
+	y int
+}
+
+// случайная строка, каждая клетка живая с вероятностью density
+func generate_row(width int, density float64, rng *rand.Rand) []byte {
+	var arr = []byte{}
+	return generate_row_rec(width, density, rng, arr)
+}
+
+func generate_row_rec(width int, density float64, rng *rand.Rand, arr []byte) []byte {
+	if len(arr) == width {
+		return arr
+	}
+
+	if rng.Float64() < density {
+		var new_arr = append(arr, 1)
+		return generate_row_rec(width, density, rng, new_arr)
+	} else {
+		var new_arr = append(arr, 0)
+		return generate_row_rec(width, density, rng, new_arr)
+	}
+}
+
+func generate_field(height int, width int, density float64, rng *rand.Rand) [][]byte {
+	var arr = [][]byte{}
+	return generate_field_rec(height, width, density, rng, arr)
+}
+
+func generate_field_rec(height int, width int, density float64, rng *rand.Rand, field [][]byte) [][]byte {
+	if len(field) == height {
+		return field
+	}
+
+	var row = generate_row(width, density, rng)
+	var new_field = append(field, row)
+
+	return generate_field_rec(height, width, density, rng, new_field)
+}
+
+// переход к новому поколению
+func next_generation(height int, width int, field [][]byte) (extender_struct, life_changes) {
+	// проверяем, есть ли смысл расширять массив клеток, тор не расширяется
+	var extended_field = extender_struct{height, width, field, 0, 0}
+	if active_topology == topology_plane {
+		extended_field = extend_field(height, width, field)
+	}
+	// меняем состояния клетки
+	var new_gen_field = active_engine(extended_field.height, extended_field.width, extended_field.field)
+	var new_gen_extender extender_struct = extender_struct{extended_field.height, extended_field.width, new_gen_field, extended_field.x_offset, extended_field.y_offset}
+	// считаем рождения и смерти для статистики
+	var changes = count_changes(extended_field.height, extended_field.width, extended_field.field, new_gen_field)
+	return new_gen_extender, changes
+}
+
+type extender_struct struct {
+	height   int
+	width    int
+	field    [][]byte
+	x_offset int
+	y_offset int
+}
+
+// проверяем, нужно ли расширение
+func extend_field(height int, width int, field [][]byte) extender_struct {
+	var first_row_neighbours = count_close_elements_in_row(0, 0, width, field[0])
+	var last_row_neighbours = count_close_elements_in_row(0, 0, width, field[height-1])
+	var first_col_neighbours = count_close_elements_in_column(0, 0, 0, height, field)
+	var last_col_neighbours = count_close_elements_in_column(width-1, 0, 0, height, field)
+
+	// prepend row
+	if first_row_neighbours == -1 {
+		var extended_field = prepend_row(width, field)
+		var extender extender_struct = extender_struct{height + 1, width, extended_field, 1, 0}
+		return extender
+	}
+	// append row
+	if last_row_neighbours == -1 {
+		empty_arr := make([]byte, width, width)
+		var extended_field = append(field, empty_arr)
+		var extender extender_struct = extender_struct{height + 1, width, extended_field, 0, 0}
+		return extender
+	}
+	// prepend column
+	if first_col_neighbours == -1 {
+		var extended_field = prepend_column(height, field)
+		var extender extender_struct = extender_struct{height, width + 1, extended_field, 0, 1}
+		return extender
+	}
+	// append column
+	if last_col_neighbours == -1 {
+		var extended_field = append_column(0, height, field, make([][]byte, 0, 0))
+		var extender extender_struct = extender_struct{height, width + 1, extended_field, 0, 0}
+		return extender
+	}
+	return extender_struct{height, width, field, 0, 0}
+}
+
+func append_column(row_idx int, height int, field [][]byte, extended_field [][]byte) [][]byte {
+	if row_idx == height {
+		return extended_field
+	}
+	var new_row = append(field[row_idx], 0)
+	var new_field = append_column(row_idx+1, height, field, append(extended_field, new_row))
+	return new_field
+}
+
+// проверяем, есть ли хотя бы 3 точки в строке рядом
+func count_close_elements_in_row(el_num int, counter int, height int, row []byte) int {
+	// если конец строки, или нашли 2 подряд идущих точки, то возвращаем
+	if el_num == height || counter == -1 {
+		return counter
+	}
+	// если нашли нужное кол-во точек, выходим из цикла
+	if counter == 2 {
+		return -1
+	}
+	// если нашли живую клетку, передаем дальше с увеличенным счетчиком
+	if row[el_num] == 1 {
+		return count_close_elements_in_row(el_num+1, counter+1, height, row)
+	}
+	// если клетка не живая, то обнуляем счетчик соседних клеток
+	return count_close_elements_in_row(el_num+1, 0, height, row)
+}
+
+// проверяем, есть ли хотя бы 3 точки в колонке рядом
+func count_close_elements_in_column(col_num int, el_num int, counter int, width int, field [][]byte) int {
+	// если конец строки, или нашли 2 подряд идущих точки, то возвращаем
+	if el_num == width || counter == -1 {
+		return counter
+	}
+	// если нашли нужное кол-во точек, выходим из цикла
+	if counter == 2 {
+		return -1
+	}
+	// если нашли живую клетку, передаем дальше с увеличенным счетчиком
+	if field[el_num][col_num] == 1 {
+		return count_close_elements_in_column(col_num, el_num+1, counter+1, width, field)
+	}
+	// если клетка не живая, то обнуляем счетчик соседних клеток
+	return count_close_elements_in_column(col_num, el_num+1, 0, width, field)
+}
+
+func prepend_column(height int, field [][]byte) [][]byte {
+	empty_arr := make([]byte, 1, 1)
+	for i := 0; i < height; i++ {
+		field[i] = append(empty_arr, field[i]...)
+	}
+
+	return field
+}
+
+func prepend_row(width int, field [][]byte) [][]byte {
+	empty_arr := make([][]byte, 0, 0)
+	sub_arr := make([]byte, width, width)
+	empty_arr = append(empty_arr, sub_arr)
+	return append(empty_arr, field...)
+}
+
+// генерируем новое поколение
+func gen_new_generation(height int, width int, field [][]byte) [][]byte {
+	var coord POS = POS{0, 0}
+	var new_field = [][]byte{}
+	var new_generation = update_field(coord, height, width, field, new_field)
+	return new_generation
+}
+
+// то же самое циклами, без рекурсии: на больших полях не растит стек
+func gen_new_generation_loop(height int, width int, field [][]byte) [][]byte {
+	var new_field = make([][]byte, height)
+	for x := 0; x < height; x++ {
+		new_field[x] = make([]byte, width)
+		for y := 0; y < width; y++ {
+			new_field[x][y] = get_next_cell_status(POS{x, y}, height, width, field)
+		}
+	}
+	return new_field
+}
+
+// движок считает следующее поколение поля того же размера
+type engine_func func(height int, width int, field [][]byte) [][]byte
+
+var engines = map[string]engine_func{
+	"recursive": gen_new_generation,
+	"loop":      gen_new_generation_loop,
+}
+
+const default_engine = "recursive"
+
+// движок, которым считает next_generation
+var active_engine engine_func = gen_new_generation
+
+// проход по элементам поля
+func update_field(coord POS, height int, width int, field [][]byte, gen_field [][]byte) [][]byte {
+	if len(gen_field) == height {
+		return gen_field
+	}
+
+	var row = update_row(coord, height, width, field)
+	var next_coord POS = POS{coord.x + 1, 0}
+	var new_field = append(gen_field, row)
+
+	return update_field(next_coord, height, width, field, new_field)
+}
+
+func update_row(coord POS, height int, width int, field [][]byte) []byte {
+	var init_row = []byte{}
+	var new_row = update_row_rec(coord, height, width, field, init_row)
+	return new_row
+}
+
+// обновляем статусы по строке и возвращаем ее полностью
+func update_row_rec(coord POS, height int, width int, field [][]byte, new_row []byte) []byte {
+	// смотрим выживет ли клетка в новом поколении
+	var new_cell_status = get_next_cell_status(coord, height, width, field)
+
+	// если строка закончилась, значит ничего не делаем
+	if coord.y >= width {
+		return new_row
+	}
+
+	var row = append(new_row, new_cell_status)
+	var next_coord POS = POS{coord.x, coord.y + 1}
+	return update_row_rec(next_coord, height, width, field, row)
+}
+
+// считаем число соседей для переданной клетки и определяем будет ли она живой
+func get_next_cell_status(coord POS, height int, width int, field [][]byte) byte {
+	// проверяем все соседей
+	var l_up = is_alive(coord.x-1, coord.y-1, height, width, field)
+	var up = is_alive(coord.x, coord.y-1, height, width, field)
+	var r_up = is_alive(coord.x+1, coord.y-1, height, width, field)
+	var l = is_alive(coord.x-1, coord.y, height, width, field)
+	var r = is_alive(coord.x+1, coord.y, height, width, field)
+	var l_down = is_alive(coord.x-1, coord.y+1, height, width, field)
+	var down = is_alive(coord.x, coord.y+1, height, width, field)
+	var r_down = is_alive(coord.x+1, coord.y+1, height, width, field)
+
+	// считаем число соседей
+	var neigbours = l_up + up + r_up + l + r + l_down + down + r_down
+	var is_i_alive = is_alive(coord.x, coord.y, height, width, field)
+
+	// смотрим, что произойдет с клеткой в новом поколении, для B3/S23:
+	// РОЖДЕНИЕ: если у пустой клетки есть 3 живых соседа, то она становится живой
+	// ЭВОЛЮЦИЯ: если у живой клетки есть 2 или 3 живых соседа, то она не меняет свое состояние
+	// СМЕРТЬ:   если у живой клетки меньше 2 или больше 3 живых соседей, то она умирает
+
+	if is_i_alive == 0 && active_rule.birth[neigbours] {
+		return 1
+	} else if is_i_alive == 1 && active_rule.survive[neigbours] {
+		return 1
+	}
+
+	return 0 // клетка умирает или остается пустой
+}
+
+// проверяем, живая ли ячейка
+func is_alive(x int, y int, height int, width int, field [][]byte) byte {
+	// на торе выход за край попадает на противоположную сторону
+	if active_topology == topology_torus {
+		x = (x%height + height) % height
+		y = (y%width + width) % width
+	}
+
+	// если вышли за поле, то там клетки нет
+	if x > height-1 || x < 0 || y > width-1 || y < 0 {
+		return 0
+	}
+
+	return field[x][y]
+}
+
+// правило клеточного автомата в нотации B/S: при каком числе соседей
+// клетка рождается и при каком выживает
+type life_rule struct {
+	birth   [9]bool
+	survive [9]bool
+}
+
+func (r life_rule) String() string {
+	var b, s strings.Builder
+	for n := 0; n <= 8; n++ {
+		if r.birth[n] {
+			fmt.Fprint(&b, n)
+		}
+		if r.survive[n] {
+			fmt.Fprint(&s, n)
+		}
+	}
+	return "B" + b.String() + "/S" + s.String()
+}
+
+// разбираем правило вида "B3/S23", регистр не важен
+func parse_rule(text string) (life_rule, error) {
+	var rule = life_rule{}
+	var parts = strings.Split(strings.ToUpper(strings.TrimSpace(text)), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "B") || !strings.HasPrefix(parts[1], "S") {
+		return rule, fmt.Errorf("rule %q: expected B<digits>/S<digits>, e.g. B3/S23", text)
+	}
+
+	var fill = func(digits string, counts *[9]bool) error {
+		for _, c := range digits {
+			if c < '0' || c > '8' {
+				return fmt.Errorf("rule %q: bad neighbour count %q", text, c)
+			}
+			counts[c-'0'] = true
+		}
+		return nil
+	}
+	if err := fill(parts[0][1:], &rule.birth); err != nil {
+		return rule, err
+	}
+	if err := fill(parts[1][1:], &rule.survive); err != nil {
+		return rule, err
+	}
+	// при B0 пустое бесконечное поле оживает целиком, расширять его нельзя
+	if rule.birth[0] {
+		return rule, fmt.Errorf("rule %q: B0 rules are not supported", text)
+	}
+	return rule, nil
+}
+
+func conway_rule() life_rule {
+	var rule, _ = parse_rule("B3/S23")
+	return rule
+}
+
+// топология поля
+const (
+	// плоскость, которая расширяется, когда клетки подходят к краю
+	topology_plane = "plane"
+	// тор: поле фиксированного размера, края склеены
+	topology_torus = "torus"
+)
+
+func parse_topology(text string) (string, error) {
+	switch strings.ToLower(text) {
+	case topology_plane:
+		return topology_plane, nil
+	case topology_torus:
+		return topology_torus, nil
+	}
+	return "", fmt.Errorf("topology %q: expected %q or %q", text, topology_plane, topology_torus)
+}
+
+// правило и топология, по которым сейчас живет поле
+var active_rule = conway_rule()
+var active_topology = topology_plane
+
+// изменения поля за одно поколение
+type life_changes struct {
+	births int
+	deaths int
+}
+
+// прямоугольник, в который помещаются все живые клетки
+type bounding_box struct {
+	min_x int
+	min_y int
+	max_x int
+	max_y int
+	empty bool
+}
+
+func (b bounding_box) size_x() int {
+	if b.empty {
+		return 0
+	}
+	return b.max_x - b.min_x + 1
+}
+
+func (b bounding_box) size_y() int {
+	if b.empty {
+		return 0
+	}
+	return b.max_y - b.min_y + 1
+}
+
+func (b bounding_box) String() string {
+	return fmt.Sprintf("%dx%d", b.size_x(), b.size_y())
+}
+
+// счетчики игры, которые показываем в HUD
+type life_stats struct {
+	generation int
+	population int
+	delta      int // изменение популяции за последнее поколение
+	births     int
+	deaths     int
+	box        bounding_box
+}
+
+// считаем, сколько клеток родилось и умерло, поля должны быть одного размера
+func count_changes(height int, width int, old_field [][]byte, new_field [][]byte) life_changes {
+	var changes = life_changes{}
+	for x := 0; x < height; x++ {
+		for y := 0; y < width; y++ {
+			if old_field[x][y] == 0 && new_field[x][y] == 1 {
+				changes.births++
+			} else if old_field[x][y] == 1 && new_field[x][y] == 0 {
+				changes.deaths++
+			}
+		}
+	}
+	return changes
+}
+
+// считаем число живых клеток на поле
+func count_population(height int, width int, field [][]byte) int {
+	var population = 0
+	for x := 0; x < height; x++ {
+		for y := 0; y < width; y++ {
+			population += int(field[x][y])
+		}
+	}
+	return population
+}
+
+// ищем прямоугольник, в котором лежат все живые клетки
+func find_bounding_box(height int, width int, field [][]byte) bounding_box {
+	var box = bounding_box{empty: true}
+	for x := 0; x < height; x++ {
+		for y := 0; y < width; y++ {
+			if field[x][y] == 0 {
+				continue
+			}
+			if box.empty {
+				box = bounding_box{x, y, x, y, false}
+				continue
+			}
+			box.min_x = min(box.min_x, x)
+			box.min_y = min(box.min_y, y)
+			box.max_x = max(box.max_x, x)
+			box.max_y = max(box.max_y, y)
+		}
+	}
+	return box
+}
+
+// копируем поле, чтобы снимок не менялся вместе с игрой
+func copy_field(field [][]byte) [][]byte {
+	var new_field = make([][]byte, len(field))
+	for i := range field {
+		new_field[i] = append([]byte{}, field[i]...)
+	}
+	return new_field
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -2,13 +2,16 @@ module life
 
 go 1.22.2
 
-require github.com/hajimehoshi/ebiten/v2 v2.7.3
+require (
+	github.com/ebitenui/ebitenui v0.5.6
+	github.com/hajimehoshi/ebiten/v2 v2.7.3
+	golang.org/x/sys v0.18.0
+)
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20240329170434-1771503ff0a8 // indirect
 	github.com/ebitengine/hideconsole v1.0.0 // indirect
 	github.com/ebitengine/purego v0.7.0 // indirect
-	github.com/ebitenui/ebitenui v0.5.6 // indirect
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20200707082815-5321531c36a2 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/hajimehoshi/ebiten v1.12.12 // indirect
@@ -17,5 +20,4 @@ require (
 	golang.org/x/image v0.15.0 // indirect
 	golang.org/x/mobile v0.0.0-20240213143359-d1f7d3436075 // indirect
 	golang.org/x/sync v0.6.0 // indirect
-	golang.org/x/sys v0.18.0 // indirect
 )
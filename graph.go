@@ -0,0 +1,188 @@
+//go:build !nogui
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// сколько последних поколений держим в истории и на графике
+	history_window = 200
+
+	// положение и размер панели с графиком в пикселях
+	graph_width  = 220
+	graph_height = 120
+	graph_y      = 10
+
+	// файл, в который выгружаем статистику
+	stats_csv_file = "life_stats.csv"
+)
+
+// зависит от размера игровой зоны, см. set_layout
+var graph_x = gameHeight*scale - graph_width - 10
+
+var population_color = color.RGBA{0, 0, 0, 255}
+var births_color = color.RGBA{40, 160, 60, 255}
+var deaths_color = color.RGBA{200, 50, 50, 255}
+var graph_background = color.RGBA{255, 255, 255, 200}
+
+// одна точка графика
+type history_point struct {
+	generation int
+	population int
+	births     int
+	deaths     int
+}
+
+// состояние поля на момент поколения, чтобы можно было к нему вернуться.
+// Храним только живые клетки (индексы field): копия всего поля на каждое поколение
+// стоила дороже самого шага на большом поле
+type history_snapshot struct {
+	height   int
+	width    int
+	cells    []POS
+	x_offset int
+	y_offset int
+	origin   POS
+	stats    life_stats
+}
+
+// запоминаем текущее поколение, старые точки выталкиваем из окна
+func (g *MyGame) record_history() {
+	var cells = field_cells(g.height, g.width, g.field, POS{})
+	g.series = append(g.series, history_point{
+		generation: g.stats.generation,
+		population: len(cells),
+		births:     g.stats.births,
+		deaths:     g.stats.deaths,
+	})
+	g.snapshots = append(g.snapshots, history_snapshot{
+		height:   g.height,
+		width:    g.width,
+		cells:    cells,
+		x_offset: g.x_offset,
+		y_offset: g.y_offset,
+		origin:   g.origin,
+		stats:    g.stats,
+	})
+
+	if len(g.series) > history_window {
+		g.series = g.series[len(g.series)-history_window:]
+		g.snapshots = g.snapshots[len(g.snapshots)-history_window:]
+	}
+}
+
+// возвращаемся к поколению из истории, все что было после него забываем
+func (g *MyGame) jump_to_history(idx int) {
+	var snapshot = g.snapshots[idx]
+	g.height = snapshot.height
+	g.width = snapshot.width
+	g.field = pattern{size_x: snapshot.height, size_y: snapshot.width, cells: snapshot.cells}.to_field()
+	g.x_offset = snapshot.x_offset
+	g.y_offset = snapshot.y_offset
+	g.origin = snapshot.origin
+	g.stats = snapshot.stats
+
+	g.series = g.series[:idx+1]
+	g.snapshots = g.snapshots[:idx+1]
+	g.is_pause = true
+	g.reset_period()
+	g.escapes.reset()
+}
+
+// обрабатываем мышь над графиком, возвращаем true, если курсор на панели
+func (g *MyGame) handle_graph_click(mx, my int) bool {
+	if !g.show_graph {
+		return false
+	}
+	if mx < graph_x || mx >= graph_x+graph_width || my < graph_y || my >= graph_y+graph_height {
+		return false
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && len(g.series) > 0 {
+		// точка i нарисована на i*graph_width/(len-1), берем ближайшую к курсору
+		var idx = ((mx-graph_x)*(len(g.series)-1)*2 + graph_width) / (2 * graph_width)
+		g.jump_to_history(min(idx, len(g.series)-1))
+	}
+	return true
+}
+
+// рисуем панель с графиком популяции, рождений и смертей
+func (g *MyGame) drawGraph(screen *ebiten.Image) {
+	ebitenutil.DrawRect(screen, float64(graph_x), graph_y, graph_width, graph_height, graph_background)
+	ebitenutil.DebugPrintAt(screen, "Population / births / deaths", graph_x+2, graph_y)
+	if len(g.series) < 2 {
+		return
+	}
+
+	// автомасштаб по максимальному значению в окне
+	var max_value = 1
+	for _, point := range g.series {
+		max_value = max(max_value, point.population, point.births, point.deaths)
+	}
+
+	var plot_top = float64(graph_y + hud_line_height)
+	var plot_height = float64(graph_height - hud_line_height)
+	var step = float64(graph_width) / float64(len(g.series)-1)
+	var to_y = func(value int) float64 {
+		return plot_top + plot_height - float64(value)*plot_height/float64(max_value)
+	}
+
+	for i := 1; i < len(g.series); i++ {
+		var x0 = float64(graph_x) + float64(i-1)*step
+		var x1 = float64(graph_x) + float64(i)*step
+		var prev = g.series[i-1]
+		var cur = g.series[i]
+		ebitenutil.DrawLine(screen, x0, to_y(prev.births), x1, to_y(cur.births), births_color)
+		ebitenutil.DrawLine(screen, x0, to_y(prev.deaths), x1, to_y(cur.deaths), deaths_color)
+		ebitenutil.DrawLine(screen, x0, to_y(prev.population), x1, to_y(cur.population), population_color)
+	}
+	ebitenutil.DebugPrintAt(screen, strconv.Itoa(max_value), graph_x+2, graph_y+hud_line_height)
+}
+
+// пишем ряд в CSV: поколение, популяция, рождения, смерти
+func write_series_csv(w io.Writer, series []history_point) error {
+	var writer = csv.NewWriter(w)
+	if err := writer.Write([]string{"generation", "population", "births", "deaths"}); err != nil {
+		return err
+	}
+	for _, point := range series {
+		var record = []string{
+			strconv.Itoa(point.generation),
+			strconv.Itoa(point.population),
+			strconv.Itoa(point.births),
+			strconv.Itoa(point.deaths),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// выгружаем текущий ряд в файл рядом с игрой
+func (g *MyGame) export_series(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := write_series_csv(file, g.series); err != nil {
+		return fmt.Errorf("write %s: %w", filename, err)
+	}
+	log.Printf("statistics saved to %s", filename)
+	return nil
+}
@@ -0,0 +1,98 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// высота строки отладочного шрифта ebitenutil
+const hud_line_height = 16
+
+// учитываем новое поколение в статистике
+func (g *MyGame) count_generation(changes life_changes) {
+	g.stats.generation++
+	g.stats.births = changes.births
+	g.stats.deaths = changes.deaths
+	g.stats.delta = changes.births - changes.deaths
+	g.rate_gens++
+	g.record_history()
+}
+
+// ищем повтор поля, при первом повторе ставим паузу, если ее включили
+func (g *MyGame) detect_period() {
+	var was_stable = g.period.stable()
+	g.period = g.detector.observe(g.height, g.width, g.field, g.origin, g.stats.generation)
+	if g.auto_pause && g.period.stable() && !was_stable {
+		g.is_pause = true
+	}
+}
+
+// раз в escape_check_interval поколений ищем улетающие корабли
+func (g *MyGame) detect_escapes() {
+	if g.stats.generation%escape_check_interval == 0 {
+		g.escapes.observe(g.height, g.width, g.field, g.origin, g.stats.generation)
+	}
+}
+
+func (g *MyGame) escapes_description() string {
+	if g.escapes.remove {
+		return g.escapes.String() + " (removed)"
+	}
+	return g.escapes.String()
+}
+
+// поле поменяли вручную, старые поколения для поиска повтора больше не годятся
+func (g *MyGame) reset_period() {
+	g.detector.reset()
+	g.period = period_result{}
+}
+
+// пересчитываем популяцию и размеры, поле могли изменить и рисованием
+func (g *MyGame) update_stats() {
+	g.stats.population = count_population(g.height, g.width, g.field)
+	g.stats.box = find_bounding_box(g.height, g.width, g.field)
+
+	// раз в секунду обновляем реальную скорость симуляции
+	var elapsed = time.Since(g.rate_start)
+	if elapsed >= time.Second {
+		g.gen_rate = float64(g.rate_gens) / elapsed.Seconds()
+		g.rate_gens = 0
+		g.rate_start = time.Now()
+	}
+}
+
+// drawHUD выводит статистику в левом нижнем углу игровой зоны
+func (g *MyGame) drawHUD(screen *ebiten.Image) {
+	lines := []string{
+		fmt.Sprintf("Generation: %d", g.stats.generation),
+		fmt.Sprintf("Population: %d (%+d)", g.stats.population, g.stats.delta),
+		fmt.Sprintf("Bounding box: %s", g.stats.box),
+		fmt.Sprintf("Status: %s", g.period_description()),
+		fmt.Sprintf("Escaped: %s", g.escapes_description()),
+		fmt.Sprintf("Speed: %.1f gen/s", g.gen_rate),
+		g.speed_description(),
+		fmt.Sprintf("FPS: %.1f", ebiten.ActualFPS()),
+		fmt.Sprintf("Rule: %s", active_rule),
+		fmt.Sprintf("Topology: %s", active_topology),
+		fmt.Sprintf("Soup: %s", g.soup),
+		fmt.Sprintf("Seed: %d", g.seed),
+	}
+
+	// последняя строка экрана занята палитрой инструментов
+	var y = screenHeight - (len(lines)+1)*hud_line_height
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(screen, line, 0, y+i*hud_line_height)
+	}
+}
+
+func (g *MyGame) period_description() string {
+	if g.auto_pause {
+		return g.period.String() + " (auto-pause)"
+	}
+	return g.period.String()
+}
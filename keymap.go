@@ -0,0 +1,297 @@
+//go:build !nogui
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// файл, которым можно переопределить клавиши
+const keymap_file = "keymap.json"
+
+// через сколько кадров удержания клавиша начинает повторяться и как часто
+const (
+	key_repeat_delay    = 15
+	key_repeat_interval = 3
+)
+
+// действия, на которые можно назначить клавиши
+const (
+	action_pause           = "pause"
+	action_speed_slow      = "speed_slow"
+	action_speed_medium    = "speed_medium"
+	action_speed_fast      = "speed_fast"
+	action_faster          = "faster"
+	action_slower          = "slower"
+	action_step            = "step"
+	action_step_many       = "step_many"
+	action_step_count_up   = "step_count_up"
+	action_step_count_down = "step_count_down"
+	action_hyperspeed      = "hyperspeed"
+	action_pan_up          = "pan_up"
+	action_pan_down        = "pan_down"
+	action_pan_left        = "pan_left"
+	action_pan_right       = "pan_right"
+	action_toggle_hud      = "toggle_hud"
+	action_toggle_graph    = "toggle_graph"
+	action_toggle_minimap  = "toggle_minimap"
+	action_auto_pause      = "auto_pause"
+	action_remove_escapes  = "remove_escapes"
+	action_analyze_emitter = "analyze_emitter"
+	action_measure_osc     = "measure_oscillator"
+	action_export_metrics  = "export_metrics"
+	action_predecessor     = "find_predecessor"
+	action_toggle_census   = "toggle_census"
+	action_export_census   = "export_census"
+	action_export_csv      = "export_csv"
+	action_export_gif      = "export_gif"
+	action_export_png      = "export_png"
+	action_export_view_png = "export_view_png"
+	action_export_sel_png  = "export_selection_png"
+	action_save_session    = "save_session"
+	action_load_session    = "load_session"
+	action_help            = "help"
+
+	action_tool_brush          = "tool_brush"
+	action_tool_line           = "tool_line"
+	action_tool_rect           = "tool_rect"
+	action_tool_rect_filled    = "tool_rect_filled"
+	action_tool_ellipse        = "tool_ellipse"
+	action_tool_ellipse_filled = "tool_ellipse_filled"
+	action_tool_fill           = "tool_fill"
+	action_tool_select         = "tool_select"
+	action_mode_draw           = "mode_draw"
+	action_mode_erase          = "mode_erase"
+	action_mode_toggle         = "mode_toggle"
+	action_brush_bigger        = "brush_bigger"
+	action_brush_smaller       = "brush_smaller"
+
+	action_new_soup       = "new_soup"
+	action_next_soup      = "next_soup"
+	action_soup_symmetry  = "soup_symmetry"
+	action_soup_placement = "soup_placement"
+	action_soup_denser    = "soup_denser"
+	action_soup_sparser   = "soup_sparser"
+	action_soup_bigger    = "soup_bigger"
+	action_soup_smaller   = "soup_smaller"
+)
+
+// клавиша с модификаторами, например Shift+N или Ctrl+S
+type key_combo struct {
+	key   ebiten.Key
+	shift bool
+	ctrl  bool
+}
+
+func (c key_combo) String() string {
+	var name = c.key.String()
+	if c.shift {
+		name = "Shift+" + name
+	}
+	if c.ctrl {
+		name = "Ctrl+" + name
+	}
+	return name
+}
+
+// разбираем строку вида "Space", "Shift+N" или "Ctrl+Shift+S"
+func parse_key_combo(text string) (key_combo, error) {
+	var combo = key_combo{}
+	var name = text
+	if rest, ok := strings.CutPrefix(name, "Ctrl+"); ok {
+		combo.ctrl = true
+		name = rest
+	}
+	if rest, ok := strings.CutPrefix(name, "Shift+"); ok {
+		combo.shift = true
+		name = rest
+	}
+	if err := combo.key.UnmarshalText([]byte(name)); err != nil {
+		return combo, fmt.Errorf("bad key %q: %w", text, err)
+	}
+	return combo, nil
+}
+
+// действие, его описание для справки и назначенные клавиши
+type key_binding struct {
+	action      string
+	description string
+	keys        []key_combo
+}
+
+// клавиши по умолчанию, в этом же порядке они выводятся в справке
+func default_keymap() []key_binding {
+	return []key_binding{
+		{action_pause, "Pause/resume", []key_combo{{ebiten.KeySpace, false, false}}},
+		{action_speed_slow, "Slow speed", []key_combo{{ebiten.Key1, false, false}}},
+		{action_speed_medium, "Medium speed", []key_combo{{ebiten.Key2, false, false}}},
+		{action_speed_fast, "Fast speed", []key_combo{{ebiten.Key3, false, false}}},
+		{action_faster, "Faster", []key_combo{{ebiten.KeyEqual, false, false}, {ebiten.KeyNumpadAdd, false, false}}},
+		{action_slower, "Slower", []key_combo{{ebiten.KeyMinus, false, false}, {ebiten.KeyNumpadSubtract, false, false}}},
+		{action_step, "Step one generation", []key_combo{{ebiten.KeyN, false, false}, {ebiten.KeyTab, false, false}}},
+		{action_step_many, "Step N generations", []key_combo{{ebiten.KeyN, true, false}, {ebiten.KeyTab, true, false}}},
+		{action_step_count_up, "Double N", []key_combo{{ebiten.KeyBracketRight, false, false}}},
+		{action_step_count_down, "Halve N", []key_combo{{ebiten.KeyBracketLeft, false, false}}},
+		{action_hyperspeed, "Hyperspeed", []key_combo{{ebiten.KeyU, false, false}}},
+		{action_pan_up, "Move up", []key_combo{{ebiten.KeyArrowUp, false, false}}},
+		{action_pan_down, "Move down", []key_combo{{ebiten.KeyArrowDown, false, false}}},
+		{action_pan_left, "Move left", []key_combo{{ebiten.KeyArrowLeft, false, false}}},
+		{action_pan_right, "Move right", []key_combo{{ebiten.KeyArrowRight, false, false}}},
+		{action_toggle_hud, "Show/hide statistics", []key_combo{{ebiten.KeyH, false, false}}},
+		{action_toggle_graph, "Show/hide graph", []key_combo{{ebiten.KeyG, false, false}}},
+		{action_toggle_minimap, "Show/hide minimap", []key_combo{{ebiten.KeyM, false, false}}},
+		{action_auto_pause, "Pause when the board stabilizes", []key_combo{{ebiten.KeyA, false, false}}},
+		{action_remove_escapes, "Delete escaping spaceships", []key_combo{{ebiten.KeyJ, false, false}}},
+		{action_analyze_emitter, "Analyze gun or puffer (pattern in hand, selection or whole board)", []key_combo{{ebiten.KeyW, false, false}}},
+		{action_measure_osc, "Oscillator metrics (pattern in hand, selection or whole board)", []key_combo{{ebiten.KeyQ, false, false}}},
+		{action_export_metrics, "Save oscillator metrics to metrics.json", []key_combo{{ebiten.KeyQ, true, false}}},
+		{action_predecessor, "Find predecessor of the selection or pattern in hand, or prove Garden of Eden", []key_combo{{ebiten.KeyZ, false, false}}},
+		{action_toggle_census, "Show/hide object census", []key_combo{{ebiten.KeyK, false, false}}},
+		{action_export_census, "Save unknown objects to census/", []key_combo{{ebiten.KeyK, true, false}}},
+		{action_export_csv, "Save graph to CSV", []key_combo{{ebiten.KeyC, false, false}}},
+		{action_export_gif, "Save next generations to GIF or APNG", []key_combo{{ebiten.KeyV, false, false}}},
+		{action_export_png, "Save board to PNG, 1 pixel per cell", []key_combo{{ebiten.KeyI, false, false}}},
+		{action_export_view_png, "Save visible area to PNG", []key_combo{{ebiten.KeyI, true, false}}},
+		{action_export_sel_png, "Save selection to PNG, 1 pixel per cell", []key_combo{{ebiten.KeyI, false, true}}},
+		{action_save_session, "Save session", []key_combo{{ebiten.KeyS, false, true}}},
+		{action_load_session, "Load session", []key_combo{{ebiten.KeyO, false, true}}},
+		{action_tool_brush, "Brush tool", []key_combo{{ebiten.KeyB, false, false}}},
+		{action_tool_line, "Line tool", []key_combo{{ebiten.KeyL, false, false}}},
+		{action_tool_rect, "Rectangle tool", []key_combo{{ebiten.KeyR, false, false}}},
+		{action_tool_rect_filled, "Filled rectangle tool", []key_combo{{ebiten.KeyR, true, false}}},
+		{action_tool_ellipse, "Ellipse tool", []key_combo{{ebiten.KeyE, false, false}}},
+		{action_tool_ellipse_filled, "Filled ellipse tool", []key_combo{{ebiten.KeyE, true, false}}},
+		{action_tool_fill, "Flood fill tool", []key_combo{{ebiten.KeyF, false, false}}},
+		{action_tool_select, "Select tool: drag a rectangle for analysis and export, click to clear", []key_combo{{ebiten.KeyBackslash, false, false}}},
+		{action_mode_draw, "Draw mode", []key_combo{{ebiten.KeyD, false, false}}},
+		{action_mode_erase, "Erase mode (right click always erases)", []key_combo{{ebiten.KeyX, false, false}}},
+		{action_mode_toggle, "Toggle mode", []key_combo{{ebiten.KeyT, false, false}}},
+		{action_brush_bigger, "Bigger brush", []key_combo{{ebiten.KeyPeriod, false, false}}},
+		{action_brush_smaller, "Smaller brush", []key_combo{{ebiten.KeyComma, false, false}}},
+		{action_new_soup, "New soup (same seed)", []key_combo{{ebiten.KeyS, false, false}}},
+		{action_next_soup, "New soup (next seed)", []key_combo{{ebiten.KeyS, true, false}}},
+		{action_soup_symmetry, "Soup symmetry", []key_combo{{ebiten.KeyY, false, false}}},
+		{action_soup_placement, "Soup placement", []key_combo{{ebiten.KeyP, false, false}}},
+		{action_soup_denser, "Denser soup", []key_combo{{ebiten.KeyQuote, false, false}}},
+		{action_soup_sparser, "Sparser soup", []key_combo{{ebiten.KeySemicolon, false, false}}},
+		{action_soup_bigger, "Bigger soup", []key_combo{{ebiten.KeyPeriod, true, false}}},
+		{action_soup_smaller, "Smaller soup", []key_combo{{ebiten.KeyComma, true, false}}},
+		{action_help, "Show/hide help", []key_combo{{ebiten.KeyF1, false, false}}},
+	}
+}
+
+// переопределяем клавиши из JSON вида {"pause": ["Space", "P"]}
+func apply_keymap_overrides(keymap []key_binding, data []byte) error {
+	var overrides = map[string][]string{}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return err
+	}
+
+	for action, names := range overrides {
+		var idx = find_binding(keymap, action)
+		if idx == -1 {
+			return fmt.Errorf("unknown action %q", action)
+		}
+		var keys = []key_combo{}
+		for _, name := range names {
+			combo, err := parse_key_combo(name)
+			if err != nil {
+				return fmt.Errorf("action %q: %w", action, err)
+			}
+			keys = append(keys, combo)
+		}
+		keymap[idx].keys = keys
+	}
+	return nil
+}
+
+// загружаем раскладку, если файла нет, остаются клавиши по умолчанию
+func load_keymap(filename string) ([]key_binding, error) {
+	var keymap = default_keymap()
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return keymap, nil
+	}
+	if err != nil {
+		return keymap, err
+	}
+	if err := apply_keymap_overrides(keymap, data); err != nil {
+		return default_keymap(), fmt.Errorf("%s: %w", filename, err)
+	}
+	return keymap, nil
+}
+
+func find_binding(keymap []key_binding, action string) int {
+	for i, binding := range keymap {
+		if binding.action == action {
+			return i
+		}
+	}
+	return -1
+}
+
+// модификаторы должны совпадать, иначе S и Ctrl+S сработают одновременно
+func (c key_combo) modifiers_match() bool {
+	return c.shift == ebiten.IsKeyPressed(ebiten.KeyShift) && c.ctrl == ebiten.IsKeyPressed(ebiten.KeyControl)
+}
+
+// действие сработало в этом кадре (клавишу только что нажали)
+func (g *MyGame) is_action_just_pressed(action string) bool {
+	var idx = find_binding(g.keymap, action)
+	if idx == -1 {
+		return false
+	}
+	for _, combo := range g.keymap[idx].keys {
+		if inpututil.IsKeyJustPressed(combo.key) && combo.modifiers_match() {
+			return true
+		}
+	}
+	return false
+}
+
+// как just pressed, но при удержании повторяется с задержкой, как в текстовых полях
+func (g *MyGame) is_action_repeated(action string) bool {
+	var idx = find_binding(g.keymap, action)
+	if idx == -1 {
+		return false
+	}
+	for _, combo := range g.keymap[idx].keys {
+		var duration = inpututil.KeyPressDuration(combo.key)
+		if duration == 0 || !combo.modifiers_match() {
+			continue
+		}
+		if duration == 1 || (duration >= key_repeat_delay && (duration-key_repeat_delay)%key_repeat_interval == 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// справка строится по текущим клавишам
+func (g *MyGame) drawHelp(screen *ebiten.Image) {
+	var lines = []string{}
+	for _, binding := range g.keymap {
+		var names = []string{}
+		for _, combo := range binding.keys {
+			names = append(names, combo.String())
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", strings.Join(names, ", "), binding.description))
+	}
+	ebitenutil.DebugPrint(screen, strings.Join(lines, "\n"))
+}
+
+// короткая подсказка, когда справка скрыта
+func (g *MyGame) help_hint() string {
+	var idx = find_binding(g.keymap, action_help)
+	if idx == -1 || len(g.keymap[idx].keys) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s: Help", g.keymap[idx].keys[0])
+}
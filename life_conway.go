@@ -1,11 +1,17 @@
+//go:build !nogui
+
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"image/color"
 	_ "image/png"
 	"log"
 	"math/rand"
+	"os"
+	"time"
 
 	"github.com/ebitenui/ebitenui"
 	"github.com/ebitenui/ebitenui/image"
@@ -14,12 +20,16 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
-const scale = 4
+// размер клетки в пикселях
+var scale = 4
 
 var black color.RGBA = color.RGBA{75, 139, 190, 255}  //95,95,95
 var white color.RGBA = color.RGBA{255, 232, 115, 255} //233,233,233
 
-const (
+// ширина панели с шаблонными фигурами справа
+const sidebar_width = 60
+
+var (
 	// реальный размер отображаемой области в пикселях
 	screenWidth  = 700
 	screenHeight = 640
@@ -29,240 +39,22 @@ const (
 	gameHeight = 640 / scale
 )
 
-type POS struct {
-	x int
-	y int
-}
-
-func generate_row(width int) []byte {
-	var arr = []byte{}
-	return generate_row_rec(width, arr)
-}
-
-func generate_row_rec(width int, arr []byte) []byte {
-	if len(arr) == width {
-		return arr
-	}
-
-	if rand.Float32() < 0.1 {
-		var new_arr = append(arr, 1)
-		return generate_row_rec(width, new_arr)
-	} else {
-		var new_arr = append(arr, 0)
-		return generate_row_rec(width, new_arr)
-	}
-}
-
-func generate_field(height int, width int) [][]byte {
-	var arr = [][]byte{}
-	return generate_field_rec(height, width, arr)
-}
-
-func generate_field_rec(height int, width int, field [][]byte) [][]byte {
-	if len(field) == height {
-		return field
-	}
-
-	var row = generate_row(width)
-	var new_field = append(field, row)
-
-	return generate_field_rec(height, width, new_field)
-}
-
-// переход к новому поколению
-func next_generation(height int, width int, field [][]byte) extender_struct {
-	// проверяем, есть ли смысл расширять массив клеток
-	var extended_field = extend_field(height, width, field)
-	// меняем состояния клетки
-	var new_gen_field = gen_new_generation(extended_field.height, extended_field.width, extended_field.field)
-	var new_gen_extender extender_struct = extender_struct{extended_field.height, extended_field.width, new_gen_field, extended_field.x_offset, extended_field.y_offset}
-	return new_gen_extender
-}
-
-type extender_struct struct {
-	height   int
-	width    int
-	field    [][]byte
-	x_offset int
-	y_offset int
-}
-
-// проверяем, нужно ли расширение
-func extend_field(height int, width int, field [][]byte) extender_struct {
-	var first_row_neighbours = count_close_elements_in_row(0, 0, width, field[0])
-	var last_row_neighbours = count_close_elements_in_row(0, 0, width, field[height-1])
-	var first_col_neighbours = count_close_elements_in_column(0, 0, 0, height, field)
-	var last_col_neighbours = count_close_elements_in_column(width-1, 0, 0, height, field)
-
-	// prepend row
-	if first_row_neighbours == -1 {
-		var extended_field = prepend_row(width, field)
-		var extender extender_struct = extender_struct{height + 1, width, extended_field, 1, 0}
-		return extender
-	}
-	// append row
-	if last_row_neighbours == -1 {
-		empty_arr := make([]byte, width, width)
-		var extended_field = append(field, empty_arr)
-		var extender extender_struct = extender_struct{height + 1, width, extended_field, 0, 0}
-		return extender
-	}
-	// prepend column
-	if first_col_neighbours == -1 {
-		var extended_field = prepend_column(height, field)
-		var extender extender_struct = extender_struct{height, width + 1, extended_field, 0, 1}
-		return extender
-	}
-	// append column
-	if last_col_neighbours == -1 {
-		var extended_field = append_column(0, height, field, make([][]byte, 0, 0))
-		var extender extender_struct = extender_struct{height, width + 1, extended_field, 0, 0}
-		return extender
-	}
-	return extender_struct{height, width, field, 0, 0}
-}
-
-func append_column(row_idx int, height int, field [][]byte, extended_field [][]byte) [][]byte {
-	if row_idx == height {
-		return extended_field
-	}
-	var new_row = append(field[row_idx], 0)
-	var new_field = append_column(row_idx+1, height, field, append(extended_field, new_row))
-	return new_field
-}
-
-// проверяем, есть ли хотя бы 3 точки в строке рядом
-func count_close_elements_in_row(el_num int, counter int, height int, row []byte) int {
-	// если конец строки, или нашли 2 подряд идущих точки, то возвращаем
-	if el_num == height || counter == -1 {
-		return counter
-	}
-	// если нашли нужное кол-во точек, выходим из цикла
-	if counter == 2 {
-		return -1
-	}
-	// если нашли живую клетку, передаем дальше с увеличенным счетчиком
-	if row[el_num] == 1 {
-		return count_close_elements_in_row(el_num+1, counter+1, height, row)
-	}
-	// если клетка не живая, то обнуляем счетчик соседних клеток
-	return count_close_elements_in_row(el_num+1, 0, height, row)
-}
-
-// проверяем, есть ли хотя бы 3 точки в колонке рядом
-func count_close_elements_in_column(col_num int, el_num int, counter int, width int, field [][]byte) int {
-	// если конец строки, или нашли 2 подряд идущих точки, то возвращаем
-	if el_num == width || counter == -1 {
-		return counter
-	}
-	// если нашли нужное кол-во точек, выходим из цикла
-	if counter == 2 {
-		return -1
-	}
-	// если нашли живую клетку, передаем дальше с увеличенным счетчиком
-	if field[el_num][col_num] == 1 {
-		return count_close_elements_in_column(col_num, el_num+1, counter+1, width, field)
-	}
-	// если клетка не живая, то обнуляем счетчик соседних клеток
-	return count_close_elements_in_column(col_num, el_num+1, 0, width, field)
-}
-
-func prepend_column(height int, field [][]byte) [][]byte {
-	empty_arr := make([]byte, 1, 1)
-	for i := 0; i < height; i++ {
-		field[i] = append(empty_arr, field[i]...)
-	}
-
-	return field
-}
+// пересчитываем размеры окна и игровой зоны, размеры панелей зависят от них
+func set_layout(window_width int, window_height int, cell_size int) {
+	scale = cell_size
+	screenWidth = window_width
+	screenHeight = window_height
+	gameHeight = (screenWidth - sidebar_width) / scale
+	gameWidth = screenHeight / scale
 
-func prepend_row(width int, field [][]byte) [][]byte {
-	empty_arr := make([][]byte, 0, 0)
-	sub_arr := make([]byte, width, width)
-	empty_arr = append(empty_arr, sub_arr)
-	return append(empty_arr, field...)
+	graph_x = gameHeight*scale - graph_width - 10
+	minimap_x = gameHeight*scale - minimap_size - 10
+	minimap_y = gameWidth*scale - minimap_size - 10
 }
 
-// генерируем новое поколение
-func gen_new_generation(height int, width int, field [][]byte) [][]byte {
-	var coord POS = POS{0, 0}
-	var new_field = [][]byte{}
-	var new_generation = update_field(coord, height, width, field, new_field)
-	return new_generation
-}
-
-// проход по элементам поля
-func update_field(coord POS, height int, width int, field [][]byte, gen_field [][]byte) [][]byte {
-	if len(gen_field) == height {
-		return gen_field
-	}
-
-	var row = update_row(coord, height, width, field)
-	var next_coord POS = POS{coord.x + 1, 0}
-	var new_field = append(gen_field, row)
-
-	return update_field(next_coord, height, width, field, new_field)
-}
-
-func update_row(coord POS, height int, width int, field [][]byte) []byte {
-	var init_row = []byte{}
-	var new_row = update_row_rec(coord, height, width, field, init_row)
-	return new_row
-}
-
-// обновляем статусы по строке и возвращаем ее полностью
-func update_row_rec(coord POS, height int, width int, field [][]byte, new_row []byte) []byte {
-	// смотрим выживет ли клетка в новом поколении
-	var new_cell_status = get_next_cell_status(coord, height, width, field)
-
-	// если строка закончилась, значит ничего не делаем
-	if coord.y >= width {
-		return new_row
-	}
-
-	var row = append(new_row, new_cell_status)
-	var next_coord POS = POS{coord.x, coord.y + 1}
-	return update_row_rec(next_coord, height, width, field, row)
-}
-
-// считаем число соседей для переданной клетки и определяем будет ли она живой
-func get_next_cell_status(coord POS, height int, width int, field [][]byte) byte {
-	// проверяем все соседей
-	var l_up = is_alive(coord.x-1, coord.y-1, height, width, field)
-	var up = is_alive(coord.x, coord.y-1, height, width, field)
-	var r_up = is_alive(coord.x+1, coord.y-1, height, width, field)
-	var l = is_alive(coord.x-1, coord.y, height, width, field)
-	var r = is_alive(coord.x+1, coord.y, height, width, field)
-	var l_down = is_alive(coord.x-1, coord.y+1, height, width, field)
-	var down = is_alive(coord.x, coord.y+1, height, width, field)
-	var r_down = is_alive(coord.x+1, coord.y+1, height, width, field)
-
-	// считаем число соседей
-	var neigbours = l_up + up + r_up + l + r + l_down + down + r_down
-	var is_i_alive = is_alive(coord.x, coord.y, height, width, field)
-
-	// смотрим, что произойдет с клеткой в новом поколении
-	// РОЖДЕНИЕ: если у пустой клетки есть 3 живых соседа, то она становится живой
-	// ЭВОЛЮЦИЯ: если у живой клетки есть 2 или 3 живых соседа, то она не меняет свое состояние
-	// СМЕРТЬ:   если у живой клетки меньше 2 или больше 3 живых соседей, то она умирает
-
-	if neigbours == 3 {
-		return 1
-	} else if (neigbours == 3 || neigbours == 2) && is_i_alive == 1 {
-		return field[coord.x][coord.y]
-	}
-
-	return 0 // клетка умирает, соседей либо > 2, либо < 3
-}
-
-// проверяем, живая ли ячейка
-func is_alive(x int, y int, height int, width int, field [][]byte) byte {
-	// если вышли за поле, то там клетки нет
-	if x > height-1 || x < 0 || y > width-1 || y < 0 {
-		return 0
-	}
-
-	return field[x][y]
+// сторона самого большого супа, который помещается в игровую зону такого окна
+func max_soup_size(window_width int, window_height int, cell_size int) int {
+	return min((window_width-sidebar_width)/cell_size, window_height/cell_size)
 }
 
 // Game implements ebiten.Game interface.
@@ -270,6 +62,11 @@ type MyGame struct {
 	// состояние игры
 	counter     int
 	max_counter int
+	// скорость: индекс в speed_levels, поколений за кадр и размер шага
+	speed_idx      int
+	gens_per_frame int
+	step_count     int
+	hyperspeed     bool
 
 	is_pause       bool
 	is_figure_draw bool
@@ -287,23 +84,139 @@ type MyGame struct {
 	// так будем показывать пиксели начиная с 250 по x и 250 по y
 	x_offset int
 	y_offset int
+	// сколько строк и колонок добавили в начало field за все время, нужно, чтобы заметить движение кораблей
+	origin POS
 
 	cursor POS
 
+	// назначенные клавиши и справка по ним
+	keymap    []key_binding
+	show_help bool
+
+	// инструменты рисования
+	tool         int
+	brush_mode   int
+	brush_size   int
+	drag_active  bool
+	drag_button  ebiten.MouseButton
+	drag_mode    int
+	drag_start   POS
+	drag_last    POS
+	stroke_cells map[POS]bool
+	// выделенный прямоугольник, empty - ничего не выделено
+	selection bounding_box
+
+	// единственный источник случайности в игре, seed показываем в HUD,
+	// чтобы по нему можно было повторить то, что видел пользователь
+	seed int64
+	rng  *rand.Rand
+
+	// настройки генератора супа
+	soup soup_settings
+
+	// счетчики для HUD
+	stats    life_stats
+	show_hud bool
+	// замер реальной скорости: сколько поколений прошло с момента rate_start
+	rate_start time.Time
+	rate_gens  int
+	gen_rate   float64
+
+	// поиск повтора поля, при повторе можно ставить паузу
+	detector   *period_detector
+	period     period_result
+	auto_pause bool
+
+	// корабли, которые улетели от основной массы
+	escapes *escape_detector
+	// окно с итогом анализа ружья или осциллятора, nil - окно закрыто
+	analysis_lines []string
+	// анализ излучателя, метрики осциллятора и поиск предшественника идут в отдельных горутинах,
+	// окно при этом не замирает
+	emitter_busy     bool
+	emitter_done     chan emitter_report
+	oscillator_busy  bool
+	oscillator_done  chan []string
+	predecessor_busy bool
+	predecessor_done chan predecessor_result
+
+	// перепись объектов на поле
+	show_census       bool
+	census            []census_entry
+	census_distance   int
+	census_generation int
+	census_busy       bool
+	census_done       chan census_update
+	census_time       time.Time
+
+	// история поколений для графика
+	show_graph bool
+	series     []history_point
+	snapshots  []history_snapshot
+
+	// миникарта всего поля
+	show_minimap   bool
+	minimap_image  *ebiten.Image
+	minimap_pixels []byte
+
+	// запись анимации клавишей
+	animation window_animation
+
+	// автосохранение для восстановления после падения
+	autosave_interval time.Duration
+	last_autosave     time.Time
+	autosave_busy     bool
+	autosave_done     chan error
+	// файл восстановления от прошлого запуска, пока пользователь не ответил, восстанавливать ли его
+	recovery_offer string
+	recovery_time  time.Time
+
 	ui *ebitenui.UI
 	// btn *widget.Button
 }
 
-func NewGame(maxInitLiveCells int) *MyGame {
+// NewGame создает игру по настройкам запуска, вся случайность берется из источника с зерном cfg.Seed
+func NewGame(maxInitLiveCells int, cfg app_config) *MyGame {
+	var seed = *cfg.Seed
+	var animation, _ = cfg.window_animation()
 	g := &MyGame{
 		counter:        10,
 		max_counter:    20,
+		speed_idx:      speed_level_slow,
+		gens_per_frame: 1,
+		step_count:     10,
 		is_pause:       true,
 		is_figure_draw: false,
 		width:          gameWidth,
 		height:         gameHeight,
 		x_offset:       0,
 		y_offset:       0,
+		show_hud:       true,
+		show_minimap:   true,
+		brush_size:     1,
+		seed:           seed,
+		rng:            rand.New(rand.NewSource(seed)),
+		soup:           default_soup_settings(seed),
+		rate_start:     time.Now(),
+		// автосохранение
+		autosave_interval: time.Duration(cfg.Autosave) * time.Second,
+		last_autosave:     time.Now(),
+		autosave_done:     make(chan error, 1),
+		// поиск повтора
+		detector:   new_board_detector(),
+		auto_pause: cfg.AutoPause,
+		escapes:    new_escape_detector(cfg.RemoveEscapes),
+		// излучатель, осциллятор и предшественник
+		emitter_done:     make(chan emitter_report, 1),
+		oscillator_done:  make(chan []string, 1),
+		predecessor_done: make(chan predecessor_result, 1),
+		// перепись
+		census_distance: cfg.CensusDistance,
+		census_done:     make(chan census_update, 1),
+		// выделение
+		selection: bounding_box{empty: true},
+		// анимация, настройки уже проверены в parse_command_line
+		animation: animation,
 		// btn:      button,
 	}
 
@@ -626,7 +539,21 @@ func NewGame(maxInitLiveCells int) *MyGame {
 		Container: rootContainer,
 	}
 	g.ui = &_ui
+
+	keymap, err := load_keymap(cfg.Keymap)
+	if err != nil {
+		log.Println(err)
+	}
+	g.keymap = keymap
+
+	g.set_speed_level(cfg.Speed)
+	g.soup.density = cfg.SoupDensity
+	g.soup.size = min(cfg.SoupSize, gameHeight, gameWidth)
+	g.soup.symmetry, _ = parse_symmetry(cfg.SoupSymmetry)
 	g.init(maxInitLiveCells)
+	if g.autosave_interval > 0 {
+		g.check_recovery()
+	}
 
 	return g
 }
@@ -641,8 +568,8 @@ func (g *MyGame) init(maxLiveCells int) {
 	}
 
 	for i := 0; i < maxLiveCells; i++ {
-		x := rand.Intn(g.height)
-		y := rand.Intn(g.width)
+		x := g.rng.Intn(g.height)
+		y := g.rng.Intn(g.width)
 		g.field[x][y] = 1
 	}
 }
@@ -650,35 +577,122 @@ func (g *MyGame) init(maxLiveCells int) {
 // Update proceeds the game state.
 // Update is called every tick (1/60 [s] by default).
 func (g *MyGame) Update() error {
+	defer g.save_on_panic()
+	// сначала спрашиваем, восстанавливать ли прошлую сессию
+	if g.recoveryKeyEvent() {
+		return nil
+	}
+
 	// обрабатываем нажатия
 	g.keyEvent()
 
+	// показываем или прячем статистику
+	if g.is_action_just_pressed(action_toggle_hud) {
+		g.show_hud = !g.show_hud
+	}
+	// показываем или прячем график
+	if g.is_action_just_pressed(action_toggle_graph) {
+		g.show_graph = !g.show_graph
+	}
+	// показываем или прячем миникарту
+	if g.is_action_just_pressed(action_toggle_minimap) {
+		g.show_minimap = !g.show_minimap
+	}
+	// пауза, когда поле перестает меняться
+	if g.is_action_just_pressed(action_auto_pause) {
+		g.auto_pause = !g.auto_pause
+	}
+	// стирать ли улетевшие корабли
+	if g.is_action_just_pressed(action_remove_escapes) {
+		g.escapes.remove = !g.escapes.remove
+	}
+	// анализ ружья или осциллятора
+	g.analysisKeyEvent()
+	// перепись объектов
+	if g.is_action_just_pressed(action_toggle_census) {
+		g.show_census = !g.show_census
+		g.census = nil
+	}
+	if g.is_action_just_pressed(action_export_census) {
+		var cells, distance = g.board_universe().cells(), g.census_distance
+		go func() {
+			if err := export_census(census_dir, cells, distance); err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+	// выгружаем статистику в CSV
+	if g.is_action_just_pressed(action_export_csv) {
+		if err := g.export_series(stats_csv_file); err != nil {
+			log.Println(err)
+		}
+	}
+	// записываем следующие поколения в GIF или APNG
+	if g.is_action_just_pressed(action_export_gif) {
+		g.export_animation()
+	}
+	// снимок поля в PNG
+	if g.is_action_just_pressed(action_export_png) {
+		if err := g.export_board_png(snapshot_file); err != nil {
+			log.Println(err)
+		}
+	}
+	if g.is_action_just_pressed(action_export_view_png) {
+		if err := g.export_view_png(snapshot_file); err != nil {
+			log.Println(err)
+		}
+	}
+	if g.is_action_just_pressed(action_export_sel_png) {
+		if err := g.export_selection_png(snapshot_file); err != nil {
+			log.Println(err)
+		}
+	}
+	// сохраняем и загружаем сессию
+	if g.is_action_just_pressed(action_save_session) {
+		if err := g.save_session(session_file); err != nil {
+			log.Println(err)
+		}
+	}
+	if g.is_action_just_pressed(action_load_session) {
+		if err := g.load_session(session_file); err != nil {
+			log.Println(err)
+		}
+	}
+	// справка по клавишам
+	if g.is_action_just_pressed(action_help) {
+		g.show_help = !g.show_help
+	}
+
 	// update the UI
 	g.ui.Update()
 
 	// рисуем пиксели, если нарисовали в игровой зоне
 	mx, my := ebiten.CursorPosition()
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-		if g.is_figure_draw {
+	var on_panel = g.handle_graph_click(mx, my) || g.handle_minimap_click(mx, my) || g.handle_census_click(mx, my)
+	if g.is_figure_draw {
+		if !on_panel && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
 			g.paintFigure(g.pixels, mx, my)
-
-		} else {
-			g.paint(mx, my)
 		}
+	} else if !on_panel || g.drag_active {
+		g.handle_tool_mouse(mx, my)
 	}
 	g.cursor = POS{
 		x: mx,
 		y: my,
 	}
 
+	g.update_stats()
+	g.update_census()
+	g.autosave()
+
 	return nil
 }
 
 func (g *MyGame) keyEvent() {
-	// полная пауза
-	if ebiten.IsKeyPressed(ebiten.KeySpace) {
-		g.is_pause = true
-		g.counter = -1
+	// пауза и продолжение
+	if g.is_action_just_pressed(action_pause) {
+		g.is_pause = !g.is_pause
+		g.counter = 0
 	}
 
 	// если пауза, то не обновляем игру
@@ -687,39 +701,42 @@ func (g *MyGame) keyEvent() {
 	}
 
 	// скорость 20
-	if ebiten.IsKeyPressed(ebiten.Key1) {
+	if g.is_action_just_pressed(action_speed_slow) {
 		g.is_pause = false
-		g.max_counter = 20
+		g.set_speed_level(speed_level_slow)
 	}
 
 	// скорость 10
-	if ebiten.IsKeyPressed(ebiten.Key2) {
+	if g.is_action_just_pressed(action_speed_medium) {
 		g.is_pause = false
-		g.max_counter = 10
+		g.set_speed_level(speed_level_medium)
 	}
 
 	// скорость 0
-	if ebiten.IsKeyPressed(ebiten.Key3) {
+	if g.is_action_just_pressed(action_speed_fast) {
 		g.is_pause = false
-		g.max_counter = 0
+		g.set_speed_level(speed_level_fast)
 	}
 
-	// переходим к следующему поколению
-	if g.counter >= g.max_counter {
-		var extender = next_generation(g.height, g.width, g.field)
-		g.field = extender.field
-		g.height = extender.height
-		g.width = extender.width
-		g.x_offset += extender.x_offset
-		g.y_offset += extender.y_offset
+	g.speedKeyEvent()
+	g.toolKeyEvent()
+	g.soupKeyEvent()
 
+	// переходим к следующему поколению
+	if !g.is_pause && g.counter >= g.max_counter {
+		g.run_frame()
 		g.counter = 0
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+	// на торе поле фиксированного размера, двигать вид некуда
+	if !g.can_pan() {
+		return
+	}
+
+	if g.is_action_repeated(action_pan_up) {
 		g.y_offset++
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+	if g.is_action_repeated(action_pan_down) {
 		g.y_offset--
 
 		// тут увеличиваем размер массива с помощью функции prepend
@@ -731,11 +748,12 @@ func (g *MyGame) keyEvent() {
 			}
 
 			g.width++
+			g.origin.y++
 			g.y_offset = 0
 		}
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+	if g.is_action_repeated(action_pan_right) {
 		g.x_offset--
 
 		// тут увеличиваем размер массива с помощью функции prepend
@@ -747,21 +765,15 @@ func (g *MyGame) keyEvent() {
 			g.field = append(empty_arr, g.field...)
 
 			g.height++
+			g.origin.x++
 			g.x_offset = 0
 		}
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+	if g.is_action_repeated(action_pan_left) {
 		g.x_offset++
 	}
 }
 
-// paint draws the brush on the given canvas image at the position (x, y).
-func (g *MyGame) paint(x, y int) {
-	if x < gameHeight*4 && y < gameWidth*4 && x > 0 && y > 0 {
-		g.field[g.x_offset+(x/4)][g.y_offset+(y/4)] = 1
-	}
-}
-
 type PIXEL struct {
 	x     int
 	y     int
@@ -769,8 +781,8 @@ type PIXEL struct {
 }
 
 func (g *MyGame) paintFigure(pixels []PIXEL, x, y int) {
-	var loc_x = g.x_offset + x/4
-	var loc_y = g.y_offset + y/4
+	var loc_x = g.x_offset + x/scale
+	var loc_y = g.y_offset + y/scale
 
 	for _, pix := range pixels {
 		if pix.x+loc_x > g.x_offset+gameHeight-1 || pix.x+loc_x < 0 || pix.y+loc_y > g.y_offset+gameWidth-1 || pix.y+loc_y < 0 {
@@ -780,6 +792,7 @@ func (g *MyGame) paintFigure(pixels []PIXEL, x, y int) {
 
 	}
 	g.is_figure_draw = false
+	g.reset_period()
 }
 
 func loadButtonImage(filename string) (*widget.ButtonImage, error) {
@@ -795,43 +808,61 @@ func loadButtonImage(filename string) (*widget.ButtonImage, error) {
 	}, nil
 }
 
+// расширяем массив field нулями, чтобы в нем было хотя бы x_size на y_size клеток
+func (g *MyGame) ensure_field_size(x_size int, y_size int) {
+	if x_size > g.height {
+		for i := 0; i < x_size-g.height; i++ {
+			g.field = append(g.field, make([]byte, g.width, g.width))
+		}
+		g.height = x_size
+	}
+
+	if y_size > g.width {
+		var delta = y_size - g.width
+		for i := 0; i < g.height; i++ {
+			for range delta {
+				g.field[i] = append(g.field[i], 0)
+			}
+		}
+		g.width = y_size
+	}
+}
+
 // Draw draws the game screen.
 // Draw is called every frame (typically 1/60[s] for 60Hz display).
 func (g *MyGame) Draw(screen *ebiten.Image) {
+	defer g.save_on_panic()
 	// очищаем экран
 	screen.Fill(white)
 	// screen.DrawImage(g.canvasImage, nil)
 
+	if g.recovery_offer != "" {
+		g.drawRecoveryOffer(screen)
+		return
+	}
+
 	// показываем подсказки об управлении
-	showHints(screen)
+	if g.show_help {
+		g.drawHelp(screen)
+	} else {
+		ebitenutil.DebugPrint(screen, g.help_hint())
+	}
+	if g.show_hud {
+		g.drawHUD(screen)
+	}
 
 	// screen.SubImage()
 	// draw the UI onto the screen
 	g.ui.Draw(screen)
 
 	// рисуем линии отделяющие шаблонные фигуры
-	ebitenutil.DrawRect(screen, screenWidth-60, 0, 10, screenHeight, color.Black)
+	ebitenutil.DrawRect(screen, float64(screenWidth-sidebar_width), 0, 10, float64(screenHeight), color.Black)
 
 	var x_size = gameHeight + g.x_offset
 	var y_size = gameWidth + g.y_offset
 
 	// если вышли за границу массива, стоит его расширить и забить нулями
-	if x_size > g.height {
-		for i := 0; i < x_size-g.height; i++ {
-			g.field = append(g.field, make([]byte, g.width, g.width))
-		}
-		g.height = x_size
-	}
-
-	if y_size > g.width {
-		var delta = y_size - g.width
-		for i := 0; i < g.height; i++ {
-			for range delta {
-				g.field[i] = append(g.field[i], 0)
-			}
-		}
-		g.width = y_size
-	}
+	g.ensure_field_size(x_size, y_size)
 
 	for x := g.x_offset; x < x_size; x++ {
 		for y := g.y_offset; y < y_size; y++ {
@@ -845,13 +876,22 @@ func (g *MyGame) Draw(screen *ebiten.Image) {
 			}
 		}
 	}
-}
 
-func showHints(screen *ebiten.Image) {
-	// Draw the message.
-	tutorial := "Space: Pause\nArrow to move\n1, 2, 3: New generation frequency (1 - slow, 3 - fast)"
-	msg := fmt.Sprintf("%s", tutorial)
-	ebitenutil.DebugPrint(screen, msg)
+	// график и миникарту рисуем поверх клеток
+	if g.show_graph {
+		g.drawGraph(screen)
+	}
+	if g.show_minimap {
+		g.drawMinimap(screen)
+	}
+	if g.show_census && !g.show_help {
+		g.drawCensus(screen)
+	}
+	if g.analysis_lines != nil {
+		g.drawAnalysis(screen)
+	}
+	g.drawSelection(screen)
+	g.drawPalette(screen)
 }
 
 // Layout takes the outside size (e.g., the window size) and returns the (logical) screen size.
@@ -861,13 +901,40 @@ func (g *MyGame) Layout(outsideWidth, outsideHeight int) (_screenWidth, _screenH
 }
 
 func main() {
+	// подкоманды работают без окна
+	if handled, code := run_subcommand(os.Args[1:], os.Stdout, os.Stderr); handled {
+		os.Exit(code)
+	}
+
+	cfg, err := parse_command_line(os.Args[0], os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "life:", err)
+		os.Exit(2)
+	}
+	apply_config(cfg)
+
+	var game = NewGame(0, cfg)
+	if cfg.Pattern != "" {
+		p, err := load_pattern(cfg.Pattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.place_pattern(p, POS{gameHeight / 2, gameWidth / 2})
+	} else if cfg.StartSoup {
+		game.new_soup()
+	}
+
 	// Specify the window size as you like. Here, a doubled size is specified.
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Conway's game of life")
 
 	// Call ebiten.RunGame to start your game loop.
 	// int((screenWidth * screenHeight) / 100)
-	if err := ebiten.RunGame(NewGame(0)); err != nil {
+	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
+	game.finish_autosave()
 }
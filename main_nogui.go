@@ -0,0 +1,17 @@
+//go:build nogui
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// сборка без окна (go build -tags nogui) для машин без дисплея: доступны только подкоманды
+func main() {
+	if handled, code := run_subcommand(os.Args[1:], os.Stdout, os.Stderr); handled {
+		os.Exit(code)
+	}
+	fmt.Fprintln(os.Stderr, "life: built without GUI, use a subcommand: run, tui, animate, census, apgcode, search, emitter, metrics, predecessor, find, benchmark")
+	os.Exit(exit_usage)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// дольше не ищем период осциллятора
+	metrics_max_period = 1000
+	// файл, в который окно сохраняет метрики
+	metrics_file = "metrics.json"
+)
+
+// характеристики натюрморта или осциллятора за один период, как на LifeWiki
+type oscillator_metrics struct {
+	Status        string `json:"status"`
+	Period        int    `json:"period"`
+	Apgcode       string `json:"apgcode,omitempty"`
+	Symmetry      string `json:"symmetry"`
+	MinPopulation int    `json:"min_population"`
+	MaxPopulation int    `json:"max_population"`
+	// ротор - клетки, которые за период меняются, статор - живые во всех фазах
+	Rotor  int `json:"rotor"`
+	Stator int `json:"stator"`
+	// сколько клеток в среднем меняется за поколение
+	Heat float64 `json:"heat"`
+	// доля ротора среди всех клеток, которые бывают живыми; строгая - только клетки с полным периодом
+	Volatility       float64 `json:"volatility"`
+	StrictVolatility float64 `json:"strict_volatility"`
+	// какая доля ротора в среднем меняется за поколение
+	Temperature float64 `json:"temperature"`
+}
+
+func (m oscillator_metrics) lines() []string {
+	return []string{
+		m.Status,
+		fmt.Sprintf("apgcode %s", m.Apgcode),
+		fmt.Sprintf("symmetry %s", m.Symmetry),
+		fmt.Sprintf("population %d..%d", m.MinPopulation, m.MaxPopulation),
+		fmt.Sprintf("rotor %d, stator %d", m.Rotor, m.Stator),
+		fmt.Sprintf("heat %.2f, temperature %.2f", m.Heat, m.Temperature),
+		fmt.Sprintf("volatility %.2f, strict %.2f", m.Volatility, m.StrictVolatility),
+	}
+}
+
+// класс симметрии, как в apgsearch: группа и где центр - в клетке (1), на середине стороны (2) или в углу (4)
+func symmetry_class(cells []POS) string {
+	var key = cells_key(normalize_cells(cells))
+	// has[i] - шаблон переходит в себя при object_transforms[i]
+	var has = make([]bool, len(object_transforms))
+	for i, transform := range object_transforms {
+		var moved = make([]POS, len(cells))
+		for j, c := range cells {
+			moved[j] = transform(c)
+		}
+		has[i] = cells_key(normalize_cells(moved)) == key
+	}
+	var box = cells_box(cells)
+	var odd_x, odd_y = box.size_x()%2 == 1, box.size_y()%2 == 1
+	var center = 4
+	if odd_x && odd_y {
+		center = 1
+	} else if odd_x || odd_y {
+		center = 2
+	}
+	// у зеркальной симметрии центр на оси: в клетке или между клетками, смотрим только ширину поперек оси
+	var mirror_center = func(odd bool) int {
+		if odd {
+			return 1
+		}
+		return 2
+	}
+
+	var flip_x, flip_y, rotate180, diagonal, rotate90, antidiagonal = has[1], has[2], has[3], has[4], has[5], has[7]
+	switch {
+	case rotate90 && flip_x:
+		return fmt.Sprintf("D8_%d", center)
+	case rotate90:
+		return fmt.Sprintf("C4_%d", center)
+	case flip_x && flip_y:
+		return fmt.Sprintf("D4_+%d", center)
+	case diagonal && antidiagonal:
+		return fmt.Sprintf("D4_x%d", center)
+	case rotate180:
+		return fmt.Sprintf("C2_%d", center)
+	case flip_x:
+		return fmt.Sprintf("D2_+%d", mirror_center(odd_x))
+	case flip_y:
+		return fmt.Sprintf("D2_+%d", mirror_center(odd_y))
+	case diagonal || antidiagonal:
+		return "D2_x"
+	}
+	return "C1"
+}
+
+// наименьший период последовательности состояний клетки, длина последовательности - период шаблона
+func sequence_period(states []bool) int {
+	var n = len(states)
+	for p := 1; p < n; p++ {
+		if n%p != 0 {
+			continue
+		}
+		var periodic = true
+		for i := 0; i < n && periodic; i++ {
+			periodic = states[i] == states[(i+p)%n]
+		}
+		if periodic {
+			return p
+		}
+	}
+	return n
+}
+
+// считаем шаблон до повтора и собираем метрики по фазам одного периода
+func measure_oscillator(cells []POS) (oscillator_metrics, error) {
+	var phases, result = pattern_phases(cells, metrics_max_period)
+	switch result.kind {
+	case period_died:
+		return oscillator_metrics{}, errors.New("pattern dies out")
+	case period_unknown:
+		return oscillator_metrics{}, fmt.Errorf("no period found in %d generations", metrics_max_period)
+	case period_spaceship:
+		return oscillator_metrics{}, fmt.Errorf("%s: rotor and stator are defined only for still lifes and oscillators", result)
+	}
+
+	var m = oscillator_metrics{
+		Status:        result.String(),
+		Period:        result.period,
+		Apgcode:       apgcode(phases, result),
+		Symmetry:      symmetry_class(phases[0]),
+		MinPopulation: len(phases[0]),
+		MaxPopulation: len(phases[0]),
+	}
+	// состояние каждой клетки, которая хоть раз была живой, по фазам
+	var states = map[POS][]bool{}
+	for i, phase := range phases {
+		m.MinPopulation = min(m.MinPopulation, len(phase))
+		m.MaxPopulation = max(m.MaxPopulation, len(phase))
+		for _, c := range phase {
+			if states[c] == nil {
+				states[c] = make([]bool, len(phases))
+			}
+			states[c][i] = true
+		}
+	}
+
+	var changes, strict = 0, 0
+	for _, s := range states {
+		var always = true
+		for i := range s {
+			always = always && s[i]
+			// после последней фазы снова идет первая
+			if s[i] != s[(i+1)%len(s)] {
+				changes++
+			}
+		}
+		if always {
+			m.Stator++
+			continue
+		}
+		m.Rotor++
+		if sequence_period(s) == len(s) {
+			strict++
+		}
+	}
+	m.Heat = float64(changes) / float64(m.Period)
+	m.Volatility = float64(m.Rotor) / float64(len(states))
+	m.StrictVolatility = float64(strict) / float64(len(states))
+	if m.Rotor > 0 {
+		m.Temperature = m.Heat / float64(m.Rotor)
+	}
+	return m, nil
+}
+
+func write_metrics_json(w io.Writer, m oscillator_metrics) error {
+	var encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+func save_metrics(filename string, m oscillator_metrics) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write_metrics_json(file, m); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", filename, err)
+	}
+	return file.Close()
+}
+
+// life metrics: симметрия, популяция, ротор и статор, heat, volatility и temperature осциллятора
+func metrics_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life metrics", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var json_file = flags.String("json", "", "also save the metrics as JSON, - prints JSON instead of text")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life metrics:", err)
+		return exit_usage
+	}
+
+	m, err := measure_oscillator(start.cells)
+	if err != nil {
+		fmt.Fprintln(stderr, "life metrics:", err)
+		return exit_error
+	}
+	if *json_file == "-" {
+		if err := write_metrics_json(stdout, m); err != nil {
+			fmt.Fprintln(stderr, "life metrics:", err)
+			return exit_error
+		}
+		return exit_ok
+	}
+	for _, line := range m.lines() {
+		fmt.Fprintln(stdout, line)
+	}
+	if *json_file != "" {
+		if err := save_metrics(*json_file, m); err != nil {
+			fmt.Fprintln(stderr, "life metrics:", err)
+			return exit_error
+		}
+	}
+	return exit_ok
+}
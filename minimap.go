@@ -0,0 +1,105 @@
+//go:build !nogui
+
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// миникарта в правом нижнем углу игровой зоны
+const minimap_size = 120
+
+// зависят от размера игровой зоны, см. set_layout
+var minimap_x = gameHeight*scale - minimap_size - 10
+var minimap_y = gameWidth*scale - minimap_size - 10
+
+var minimap_border = color.RGBA{0, 0, 0, 255}
+var minimap_viewport = color.RGBA{200, 50, 50, 255}
+
+// во сколько раз уменьшаем поле, чтобы оно целиком влезло в миникарту
+func (g *MyGame) minimap_factor() float64 {
+	var longest = max(g.height, g.width, 1)
+	return float64(minimap_size) / float64(longest)
+}
+
+// вид можно двигать только по расширяемой плоскости
+func (g *MyGame) can_pan() bool {
+	return active_topology == topology_plane
+}
+
+// обрабатываем мышь над миникартой, возвращаем true, если курсор на ней
+func (g *MyGame) handle_minimap_click(mx, my int) bool {
+	if !g.show_minimap {
+		return false
+	}
+	if mx < minimap_x || mx >= minimap_x+minimap_size || my < minimap_y || my >= minimap_y+minimap_size {
+		return false
+	}
+
+	// нажатие или перетаскивание переносит камеру в эту точку
+	if g.can_pan() && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		var factor = g.minimap_factor()
+		var field_x = int(float64(mx-minimap_x) / factor)
+		var field_y = int(float64(my-minimap_y) / factor)
+		g.x_offset = max(0, field_x-gameHeight/2)
+		g.y_offset = max(0, field_y-gameWidth/2)
+	}
+	return true
+}
+
+// рисуем все живые клетки поля в уменьшенном виде и рамку текущего вида
+func (g *MyGame) drawMinimap(screen *ebiten.Image) {
+	if g.minimap_image == nil {
+		g.minimap_image = ebiten.NewImage(minimap_size, minimap_size)
+		g.minimap_pixels = make([]byte, minimap_size*minimap_size*4)
+	}
+
+	// фон
+	for i := 0; i < len(g.minimap_pixels); i += 4 {
+		g.minimap_pixels[i] = white.R
+		g.minimap_pixels[i+1] = white.G
+		g.minimap_pixels[i+2] = white.B
+		g.minimap_pixels[i+3] = 255
+	}
+
+	var factor = g.minimap_factor()
+	for x := 0; x < g.height; x++ {
+		for y := 0; y < g.width; y++ {
+			if g.field[x][y] == 0 {
+				continue
+			}
+			var px = min(int(float64(x)*factor), minimap_size-1)
+			var py = min(int(float64(y)*factor), minimap_size-1)
+			var idx = (py*minimap_size + px) * 4
+			g.minimap_pixels[idx] = black.R
+			g.minimap_pixels[idx+1] = black.G
+			g.minimap_pixels[idx+2] = black.B
+		}
+	}
+	g.minimap_image.WritePixels(g.minimap_pixels)
+
+	var op = &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(minimap_x), float64(minimap_y))
+	screen.DrawImage(g.minimap_image, op)
+
+	// рамка миникарты
+	var left = float64(minimap_x)
+	var top = float64(minimap_y)
+	ebitenutil.DrawRect(screen, left-1, top-1, minimap_size+2, 1, minimap_border)
+	ebitenutil.DrawRect(screen, left-1, top+minimap_size, minimap_size+2, 1, minimap_border)
+	ebitenutil.DrawRect(screen, left-1, top-1, 1, minimap_size+2, minimap_border)
+	ebitenutil.DrawRect(screen, left+minimap_size, top-1, 1, minimap_size+2, minimap_border)
+
+	// прямоугольник текущего вида
+	var view_x = left + float64(g.x_offset)*factor
+	var view_y = top + float64(g.y_offset)*factor
+	var view_w = float64(gameHeight) * factor
+	var view_h = float64(gameWidth) * factor
+	ebitenutil.DrawLine(screen, view_x, view_y, view_x+view_w, view_y, minimap_viewport)
+	ebitenutil.DrawLine(screen, view_x, view_y+view_h, view_x+view_w, view_y+view_h, minimap_viewport)
+	ebitenutil.DrawLine(screen, view_x, view_y, view_x, view_y+view_h, minimap_viewport)
+	ebitenutil.DrawLine(screen, view_x+view_w, view_y, view_x+view_w, view_y+view_h, minimap_viewport)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// длина строки RLE, как принято в Golly
+const rle_line_length = 70
+
+// шаблон: живые клетки относительно левого верхнего угла
+type pattern struct {
+	size_x int
+	size_y int
+	cells  []POS
+}
+
+// вырезаем шаблон из прямоугольника поля
+func pattern_from_field(field [][]byte, box bounding_box) pattern {
+	var p = pattern{size_x: box.size_x(), size_y: box.size_y()}
+	if box.empty {
+		return p
+	}
+	for x := box.min_x; x <= box.max_x; x++ {
+		for y := box.min_y; y <= box.max_y; y++ {
+			if field[x][y] == 1 {
+				p.cells = append(p.cells, POS{x - box.min_x, y - box.min_y})
+			}
+		}
+	}
+	return p
+}
+
+// шаблон из клеток в любых координатах, угол сдвигаем в (0, 0)
+func pattern_from_cells(cells []POS) pattern {
+	var p = pattern{cells: normalize_cells(cells)}
+	for _, c := range p.cells {
+		p.size_x = max(p.size_x, c.x+1)
+		p.size_y = max(p.size_y, c.y+1)
+	}
+	return p
+}
+
+// раскладываем шаблон в отдельное поле size_x на size_y
+func (p pattern) to_field() [][]byte {
+	var field = make([][]byte, p.size_x)
+	for x := range field {
+		field[x] = make([]byte, p.size_y)
+	}
+	for _, cell := range p.cells {
+		field[cell.x][cell.y] = 1
+	}
+	return field
+}
+
+// читаем RLE: комментарии #, заголовок "x = .., y = .., rule = ..", затем b/o/$ до "!"
+func parse_rle(r io.Reader) (pattern, error) {
+	var p = pattern{}
+	var scanner = bufio.NewScanner(r)
+	var header_seen = false
+	var x, y, run = 0, 0, 0
+
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !header_seen {
+			if !strings.HasPrefix(line, "x") {
+				return p, fmt.Errorf("rle: missing header line")
+			}
+			for _, part := range strings.Split(line, ",") {
+				var key, value, _ = strings.Cut(part, "=")
+				var n, err = strconv.Atoi(strings.TrimSpace(value))
+				switch strings.TrimSpace(key) {
+				case "x":
+					if err != nil {
+						return p, fmt.Errorf("rle: bad width %q", value)
+					}
+					p.size_x = n
+				case "y":
+					if err != nil {
+						return p, fmt.Errorf("rle: bad height %q", value)
+					}
+					p.size_y = n
+				}
+			}
+			header_seen = true
+			continue
+		}
+
+		for _, c := range line {
+			switch {
+			case c >= '0' && c <= '9':
+				run = run*10 + int(c-'0')
+			case c == 'b' || c == '.':
+				x += max(run, 1)
+				run = 0
+			case c == '$':
+				y += max(run, 1)
+				x = 0
+				run = 0
+			case c == '!':
+				return p, nil
+			case c == ' ' || c == '\t':
+			default:
+				// все, кроме пустых клеток, считаем живыми
+				for i := 0; i < max(run, 1); i++ {
+					p.cells = append(p.cells, POS{x + i, y})
+				}
+				x += max(run, 1)
+				p.size_x = max(p.size_x, x)
+				p.size_y = max(p.size_y, y+1)
+				run = 0
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return p, err
+	}
+	if !header_seen {
+		return p, fmt.Errorf("rle: empty pattern")
+	}
+	return p, nil
+}
+
+// читаем plaintext (.cells): строки из . и O, комментарии начинаются с !
+func parse_cells(r io.Reader) (pattern, error) {
+	var p = pattern{}
+	var scanner = bufio.NewScanner(r)
+	var y = 0
+	for scanner.Scan() {
+		var line = strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		for x, c := range line {
+			if c == 'O' || c == 'o' || c == '*' {
+				p.cells = append(p.cells, POS{x, y})
+			}
+		}
+		p.size_x = max(p.size_x, len(line))
+		y++
+	}
+	p.size_y = y
+	return p, scanner.Err()
+}
+
+// несколько одинаковых клеток подряд
+type rle_run struct {
+	count int
+	tag   byte
+}
+
+// пишем шаблон в RLE
+func write_rle(w io.Writer, p pattern, rule life_rule) error {
+	var field = p.to_field()
+	var body strings.Builder
+	var line_len = 0
+	var emit = func(count int, tag byte) {
+		var token = string(tag)
+		if count > 1 {
+			token = strconv.Itoa(count) + token
+		}
+		if line_len+len(token) > rle_line_length {
+			body.WriteString("\n")
+			line_len = 0
+		}
+		body.WriteString(token)
+		line_len += len(token)
+	}
+
+	var pending_rows, written = 0, false
+	for y := 0; y < p.size_y; y++ {
+		// последовательности одинаковых клеток в строке, пустой хвост не пишем
+		var runs = []rle_run{}
+		for x := 0; x < p.size_x; x++ {
+			var tag = byte('b')
+			if field[x][y] == 1 {
+				tag = 'o'
+			}
+			if len(runs) > 0 && runs[len(runs)-1].tag == tag {
+				runs[len(runs)-1].count++
+			} else {
+				runs = append(runs, rle_run{1, tag})
+			}
+		}
+		if len(runs) > 0 && runs[len(runs)-1].tag == 'b' {
+			runs = runs[:len(runs)-1]
+		}
+		if len(runs) == 0 {
+			pending_rows++
+			continue
+		}
+		// перед первой строкой с клетками - только пустые строки, после остальных - еще и конец строки
+		if written {
+			emit(pending_rows+1, '$')
+		} else if pending_rows > 0 {
+			emit(pending_rows, '$')
+		}
+		pending_rows, written = 0, true
+		for _, r := range runs {
+			emit(r.count, r.tag)
+		}
+	}
+	emit(1, '!')
+
+	_, err := fmt.Fprintf(w, "x = %d, y = %d, rule = %s\n%s\n", p.size_x, p.size_y, rule, body.String())
+	return err
+}
+
+// загружаем шаблон из файла, формат определяем по расширению;
+// вместо имени файла можно указать apgcode, например xq4_153
+func load_pattern(filename string) (pattern, error) {
+	if _, err := os.Stat(filename); err != nil && is_apgcode(filename) {
+		return parse_apgcode(filename)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return pattern{}, err
+	}
+	defer file.Close()
+
+	var p pattern
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".cells", ".txt":
+		p, err = parse_cells(file)
+	case ".png", ".gif":
+		p, err = parse_bitmap(file, bitmap_threshold)
+	default:
+		p, err = parse_rle(file)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", filename, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// шаблон, записанный в RLE и прочитанный обратно, не сдвигается, даже если сверху и слева пусто
+func TestRleRoundTrip(t *testing.T) {
+	var tests = []pattern{
+		{size_x: 10, size_y: 8, cells: []POS{{4, 3}, {5, 3}, {6, 3}}},
+		{size_x: 10, size_y: 8, cells: []POS{{0, 0}, {9, 7}}},
+		{size_x: 5, size_y: 12, cells: []POS{{2, 1}, {3, 5}, {0, 11}}},
+		{size_x: 3, size_y: 3},
+	}
+	for _, p := range tests {
+		var rle strings.Builder
+		if err := write_rle(&rle, p, conway_rule()); err != nil {
+			t.Fatal(err)
+		}
+		back, err := parse_rle(strings.NewReader(rle.String()))
+		if err != nil {
+			t.Fatalf("%v: %v", p.cells, err)
+		}
+		if back.size_x != p.size_x || back.size_y != p.size_y || !slices.Equal(sorted_cells(back.cells), sorted_cells(p.cells)) {
+			t.Errorf("%v written as %q reads back as %dx%d %v", p.cells, rle.String(), back.size_x, back.size_y, back.cells)
+		}
+	}
+}
+
+// клетки по порядку, чтобы сравнивать наборы
+func sorted_cells(cells []POS) []POS {
+	cells = slices.Clone(cells)
+	slices.SortFunc(cells, func(a, b POS) int {
+		if a.x != b.x {
+			return a.x - b.x
+		}
+		return a.y - b.y
+	})
+	return cells
+}
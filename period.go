@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+)
+
+// что стало с полем
+const (
+	period_unknown = iota // еще меняется
+	period_died
+	period_still
+	period_oscillator
+	period_spaceship
+)
+
+// результат поиска повтора: вид, период и сдвиг за период для кораблей
+type period_result struct {
+	kind   int
+	period int
+	dx     int
+	dy     int
+}
+
+// поле перестало меняться: умерло, застыло, осциллирует или улетает кораблем
+func (r period_result) stable() bool {
+	return r.kind != period_unknown
+}
+
+func (r period_result) String() string {
+	switch r.kind {
+	case period_died:
+		return "died out"
+	case period_still:
+		return "still life"
+	case period_oscillator:
+		return fmt.Sprintf("oscillator period %d", r.period)
+	case period_spaceship:
+		return fmt.Sprintf("spaceship period %d moving (%d,%d)", r.period, r.dx, r.dy)
+	}
+	return "running"
+}
+
+// хеш формы поля без учета положения: клетки считаем от угла прямоугольника вокруг живых
+func shape_hash(height int, width int, field [][]byte) (uint64, bounding_box) {
+	var box = find_bounding_box(height, width, field)
+	var hash = fnv.New64a()
+	if box.empty {
+		return hash.Sum64(), box
+	}
+	var buf = make([]byte, 16)
+	for x := box.min_x; x <= box.max_x; x++ {
+		for y := box.min_y; y <= box.max_y; y++ {
+			if field[x][y] == 0 {
+				continue
+			}
+			binary.LittleEndian.PutUint64(buf[0:8], uint64(x-box.min_x))
+			binary.LittleEndian.PutUint64(buf[8:16], uint64(y-box.min_y))
+			hash.Write(buf)
+		}
+	}
+	return hash.Sum64(), box
+}
+
+// хеш формы клеток в любом порядке от угла прямоугольника вокруг них
+func cells_shape_hash(cells []POS) (uint64, bounding_box) {
+	var box = cells_box(cells)
+	var sorted = slices.Clone(cells)
+	slices.SortFunc(sorted, func(a, b POS) int {
+		if a.x != b.x {
+			return a.x - b.x
+		}
+		return a.y - b.y
+	})
+	var hash = fnv.New64a()
+	var buf = make([]byte, 16)
+	for _, c := range sorted {
+		binary.LittleEndian.PutUint64(buf[0:8], uint64(c.x-box.min_x))
+		binary.LittleEndian.PutUint64(buf[8:16], uint64(c.y-box.min_y))
+		hash.Write(buf)
+	}
+	return hash.Sum64(), box
+}
+
+// когда форма встречалась и где тогда был ее угол
+type period_seen struct {
+	generation int
+	corner     POS
+}
+
+// помним формы последних поколений; одна и та же форма на том же месте - осциллятор,
+// на другом - корабль
+type period_detector struct {
+	window int
+	// не учитываем корабли, которые летят отдельно от остального поля: иначе поле, из которого
+	// улетел хоть один планер, никогда не повторится. Если кроме кораблей ничего нет, смотрим все поле
+	skip_ships bool
+	seen       map[uint64]period_seen
+	order      []uint64
+}
+
+func new_period_detector(window int) *period_detector {
+	return &period_detector{window: window, seen: map[uint64]period_seen{}}
+}
+
+// детектор для всего поля, улетающие корабли не мешают заметить, что остальное устоялось
+func new_board_detector() *period_detector {
+	var d = new_period_detector(stability_window)
+	d.skip_ships = true
+	return d
+}
+
+// забываем историю, например после того, как поле поменяли рисованием
+func (d *period_detector) reset() {
+	if len(d.order) == 0 {
+		return
+	}
+	d.seen = map[uint64]period_seen{}
+	d.order = nil
+}
+
+// учитываем поколение, origin - сдвиг field относительно абсолютных координат, как в universe
+func (d *period_detector) observe(height int, width int, field [][]byte, origin POS, generation int) period_result {
+	var hash, box = shape_hash(height, width, field)
+	if box.empty {
+		return period_result{kind: period_died}
+	}
+	var corner = POS{box.min_x - origin.x, box.min_y - origin.y}
+	if d.skip_ships {
+		if rest := without_ships(field_cells(height, width, field, origin)); len(rest) > 0 {
+			var rest_box bounding_box
+			hash, rest_box = cells_shape_hash(rest)
+			corner = POS{rest_box.min_x, rest_box.min_y}
+		}
+	}
+
+	// период считаем от последнего такого же состояния
+	if previous, ok := d.seen[hash]; ok {
+		d.seen[hash] = period_seen{generation, corner}
+		var result = period_result{
+			period: generation - previous.generation,
+			dx:     corner.x - previous.corner.x,
+			dy:     corner.y - previous.corner.y,
+		}
+		switch {
+		case result.dx != 0 || result.dy != 0:
+			result.kind = period_spaceship
+		case result.period == 1:
+			result.kind = period_still
+		default:
+			result.kind = period_oscillator
+		}
+		return result
+	}
+	d.seen[hash] = period_seen{generation, corner}
+	d.order = append(d.order, hash)
+	if len(d.order) > d.window {
+		delete(d.seen, d.order[0])
+		d.order = d.order[1:]
+	}
+	return period_result{}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// на сколько клеток вокруг шаблона может выходить предшественник
+	predecessor_margin = 1
+	// сколько предшественников ищем по умолчанию
+	predecessor_count = 1
+	// сколько по умолчанию ищем
+	predecessor_timeout = 10 * time.Second
+)
+
+// итог поиска предшественников
+type predecessor_result struct {
+	// найденные предшественники в координатах шаблона, могут выходить за его угол (0, 0)
+	predecessors [][]POS
+	// перебор закончился: найдены все нужные предшественники или доказано, что больше нет
+	complete bool
+	// у прямоугольника шаблона нет предшественника ни при каком окружении - это сирота, Сад Эдема
+	orphan bool
+}
+
+func (r predecessor_result) lines(margin int) []string {
+	var lines = []string{}
+	if len(r.predecessors) > 0 {
+		lines = append(lines, fmt.Sprintf("predecessors found: %d", len(r.predecessors)))
+	}
+	switch {
+	case r.orphan:
+		lines = append(lines, "Garden of Eden: no predecessor exists for this box in any surroundings")
+	case len(r.predecessors) == 0 && r.complete:
+		lines = append(lines, fmt.Sprintf("no predecessor within %d cells around the pattern", margin))
+	case !r.complete:
+		lines = append(lines, fmt.Sprintf("search stopped: %s", errSatTimeout))
+	}
+	return lines
+}
+
+// задача о предшественнике: переменная на каждую клетку прямоугольника vars,
+// клетки вне него в прошлом поколении мертвы
+type predecessor_problem struct {
+	vars   bounding_box
+	solver *sat_solver
+}
+
+func (p *predecessor_problem) variable(c POS) int {
+	if c.x < p.vars.min_x || c.x > p.vars.max_x || c.y < p.vars.min_y || c.y > p.vars.max_y {
+		return 0
+	}
+	return 1 + (c.x-p.vars.min_x)*p.vars.size_y() + (c.y - p.vars.min_y)
+}
+
+// кодируем правило для одной клетки c, want - какой она должна стать в следующем поколении
+func (p *predecessor_problem) constrain(c POS, want bool, rule life_rule) {
+	var neighbours = []int{}
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			if dx != 0 || dy != 0 {
+				neighbours = append(neighbours, p.variable(POS{c.x + dx, c.y + dy}))
+			}
+		}
+	}
+	encode_life_cell(p.solver, p.variable(c), neighbours, 0, want, rule)
+}
+
+// правило для одной клетки через унарный счетчик соседей: center и neighbours - переменные клетки и соседей
+// в текущем поколении, 0 - клетка всегда мертва; next - переменная клетки в следующем поколении,
+// 0 - ее значение задано заранее и равно want
+func encode_life_cell(s *sat_solver, center int, neighbours []int, next int, want bool, rule life_rule) {
+	var inputs = []int{}
+	for _, v := range neighbours {
+		if v != 0 {
+			inputs = append(inputs, v)
+		}
+	}
+	var count = s.unary_count(inputs)
+	var states = []bool{false}
+	if center != 0 {
+		states = append(states, true)
+	}
+	for k := 0; k <= len(count); k++ {
+		for _, alive := range states {
+			var result = (!alive && rule.birth[k]) || (alive && rule.survive[k])
+			if next == 0 && result == want {
+				continue
+			}
+			// ровно k живых соседей и клетка alive - клетка становится result
+			var clause = []int{}
+			if k > 0 {
+				clause = append(clause, -count[k-1])
+			}
+			if k < len(count) {
+				clause = append(clause, count[k])
+			}
+			if alive {
+				clause = append(clause, -center)
+			} else if center != 0 {
+				clause = append(clause, center)
+			}
+			if next != 0 {
+				if result {
+					clause = append(clause, next)
+				} else {
+					clause = append(clause, -next)
+				}
+			}
+			s.add_clause(clause)
+		}
+	}
+}
+
+// область vars вокруг прямоугольника шаблона, условия - на клетках прямоугольника checked
+func new_predecessor_problem(target []POS, vars bounding_box, checked bounding_box, rule life_rule) *predecessor_problem {
+	var p = &predecessor_problem{vars: vars, solver: new_sat_solver(vars.size_x() * vars.size_y())}
+	var alive = map[POS]bool{}
+	for _, c := range target {
+		alive[c] = true
+	}
+	for x := checked.min_x; x <= checked.max_x; x++ {
+		for y := checked.min_y; y <= checked.max_y; y++ {
+			p.constrain(POS{x, y}, alive[POS{x, y}], rule)
+		}
+	}
+	return p
+}
+
+func (p *predecessor_problem) cells(model []bool) []POS {
+	var cells = []POS{}
+	for x := p.vars.min_x; x <= p.vars.max_x; x++ {
+		for y := p.vars.min_y; y <= p.vars.max_y; y++ {
+			if model[p.variable(POS{x, y})] {
+				cells = append(cells, POS{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+func grow_box(b bounding_box, n int) bounding_box {
+	return bounding_box{b.min_x - n, b.min_y - n, b.max_x + n, b.max_y + n, b.empty}
+}
+
+// ищем до count шаблонов, из которых за одно поколение получается target на пустом поле;
+// предшественник помещается в прямоугольник шаблона, расширенный на margin клеток.
+// Если таких нет, проверяем, есть ли у прямоугольника шаблона предшественник хоть при каком-то окружении
+func find_predecessors(target pattern, margin int, count int, timeout time.Duration, rule life_rule) predecessor_result {
+	var deadline = time.Now().Add(timeout)
+	var box = bounding_box{0, 0, target.size_x - 1, target.size_y - 1, false}
+	var result = predecessor_result{}
+
+	// клетки вокруг области тоже должны остаться пустыми, иначе шаблон окажется не на пустом поле
+	var vars = grow_box(box, margin)
+	var problem = new_predecessor_problem(target.cells, vars, grow_box(vars, 1), rule)
+	problem.solver.deadline = deadline
+	for len(result.predecessors) < count {
+		found, err := problem.solver.solve()
+		if err != nil {
+			return result
+		}
+		if !found {
+			break
+		}
+		var model = problem.solver.model()
+		result.predecessors = append(result.predecessors, problem.cells(model))
+		// запрещаем найденный набор и ищем следующий
+		// вспомогательные переменные счетчиков идут после клеток, их значения следуют из клеток
+		var block = make([]int, vars.size_x()*vars.size_y())
+		for v := 1; v <= len(block); v++ {
+			if model[v] {
+				block[v-1] = -v
+			} else {
+				block[v-1] = v
+			}
+		}
+		problem.solver.add_clause(block)
+	}
+	result.complete = true
+	if len(result.predecessors) > 0 {
+		return result
+	}
+
+	// сирота: даже без требования пустоты вокруг у прямоугольника нет предшественника
+	var orphan = new_predecessor_problem(target.cells, grow_box(box, 1), box, rule)
+	orphan.solver.deadline = deadline
+	found, err := orphan.solver.solve()
+	if errors.Is(err, errSatTimeout) {
+		result.complete = false
+		return result
+	}
+	result.orphan = !found
+	return result
+}
+
+// life predecessor: шаблоны, из которых за одно поколение получается данный, или доказательство, что их нет
+func predecessor_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life predecessor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var margin = flags.Int("margin", predecessor_margin, "cells around the pattern a predecessor may use")
+	var count = flags.Int("count", predecessor_count, "number of predecessors to find")
+	var timeout = flags.Duration("timeout", predecessor_timeout, "time limit")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	if *margin < 0 || *count < 1 {
+		fmt.Fprintln(stderr, "life predecessor: margin must not be negative and count must be positive")
+		return exit_usage
+	}
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life predecessor:", err)
+		return exit_usage
+	}
+
+	var result = find_predecessors(start, *margin, *count, *timeout, active_rule)
+	for i, cells := range result.predecessors {
+		fmt.Fprintf(stdout, "#C predecessor %d\n", i+1)
+		if err := write_rle(stdout, pattern_from_cells(cells), active_rule); err != nil {
+			fmt.Fprintln(stderr, "life predecessor:", err)
+			return exit_error
+		}
+	}
+	for _, line := range result.lines(*margin) {
+		fmt.Fprintf(stdout, "#C %s\n", line)
+	}
+	if !result.complete {
+		return exit_error
+	}
+	return exit_ok
+}
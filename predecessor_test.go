@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// сирота 16 на 16: найден случайным перебором супов плотности 0.7 и проверен TestOrphanNaiveEncoding
+const orphan_rle = `x = 16, y = 16, rule = B3/S23
+ob4o3bo2b4o$12ob2o$o2bobob2ob4obo$2ob6ob6o$3ob6o2b4o$b3ob3ob3o2b2o$4o
+2b3obob2obo$ob9o2b2o$5obob3o2b2o$3ob3ob3ob4o$2b3o2b3obobobo$2ob3obobo
+3b3o$4ob2ob8o$3ob2obob2o2b2o$ob11ob2o$4obob4o2b3o!`
+
+// у блинкера столько же предшественников в прямоугольнике с отступом 1, сколько дает полный перебор
+func TestBlinkerPredecessors(t *testing.T) {
+	var blinker = pattern{size_x: 1, size_y: 3, cells: []POS{{0, 0}, {0, 1}, {0, 2}}}
+	var vars = grow_box(bounding_box{0, 0, 0, 2, false}, 1)
+
+	var expected = 0
+	var region = vars.size_x() * vars.size_y()
+	for bits := 0; bits < 1<<region; bits++ {
+		var cells = []POS{}
+		for i := 0; i < region; i++ {
+			if bits>>i&1 == 1 {
+				cells = append(cells, POS{vars.min_x + i/vars.size_y(), vars.min_y + i%vars.size_y()})
+			}
+		}
+		if next_matches(step_cells(cells), blinker.cells) {
+			expected++
+		}
+	}
+
+	var result = find_predecessors(blinker, 1, 1000, time.Minute, conway_rule())
+	if !result.complete || result.orphan {
+		t.Fatalf("complete %v, orphan %v", result.complete, result.orphan)
+	}
+	if len(result.predecessors) != expected {
+		t.Errorf("%d predecessors, brute force finds %d", len(result.predecessors), expected)
+	}
+	var seen = map[string]bool{}
+	var vertical = false
+	for _, p := range result.predecessors {
+		if !next_matches(step_cells(p), blinker.cells) {
+			t.Errorf("%v does not become the blinker", p)
+		}
+		var key = cells_key(p)
+		if seen[key] {
+			t.Errorf("%v found twice", p)
+		}
+		seen[key] = true
+		vertical = vertical || key == cells_key([]POS{{-1, 1}, {0, 1}, {1, 1}})
+	}
+	if !vertical {
+		t.Error("the other phase of the blinker is not among the predecessors")
+	}
+}
+
+// клетки совпадают без сдвига
+func next_matches(next []POS, target []POS) bool {
+	var alive = map[POS]bool{}
+	for _, c := range next {
+		alive[c] = true
+	}
+	if len(alive) != len(target) {
+		return false
+	}
+	for _, c := range target {
+		if !alive[c] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGardenOfEden(t *testing.T) {
+	p, err := parse_rle(strings.NewReader(orphan_rle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result = find_predecessors(p, 0, 1, time.Minute, conway_rule())
+	if !result.complete || !result.orphan || len(result.predecessors) > 0 {
+		t.Errorf("complete %v, orphan %v, %d predecessors", result.complete, result.orphan, len(result.predecessors))
+	}
+}
+
+// та же задача без счетчиков: для каждой клетки запрещаем все окрестности 3 на 3, которые дают не то
+func TestOrphanNaiveEncoding(t *testing.T) {
+	if testing.Short() {
+		t.Skip("several seconds")
+	}
+	p, err := parse_rle(strings.NewReader(orphan_rle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var box = bounding_box{0, 0, p.size_x - 1, p.size_y - 1, false}
+	var problem = &predecessor_problem{vars: grow_box(box, 1)}
+	problem.solver = new_sat_solver(problem.vars.size_x() * problem.vars.size_y())
+	var alive = map[POS]bool{}
+	for _, c := range p.cells {
+		alive[c] = true
+	}
+	var rule = conway_rule()
+	for x := box.min_x; x <= box.max_x; x++ {
+		for y := box.min_y; y <= box.max_y; y++ {
+			var vars = []int{}
+			for dx := -1; dx <= 1; dx++ {
+				for dy := -1; dy <= 1; dy++ {
+					vars = append(vars, problem.variable(POS{x + dx, y + dy}))
+				}
+			}
+			for bits := 0; bits < 1<<9; bits++ {
+				var neighbours = 0
+				for i := 0; i < 9; i++ {
+					if i != 4 && bits>>i&1 == 1 {
+						neighbours++
+					}
+				}
+				var center = bits>>4&1 == 1
+				if ((center && rule.survive[neighbours]) || (!center && rule.birth[neighbours])) == alive[POS{x, y}] {
+					continue
+				}
+				var clause = []int{}
+				for i, v := range vars {
+					if bits>>i&1 == 1 {
+						clause = append(clause, -v)
+					} else {
+						clause = append(clause, v)
+					}
+				}
+				problem.solver.add_clause(clause)
+			}
+		}
+	}
+	found, err := problem.solver.solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Errorf("predecessor %v", problem.cells(problem.solver.model()))
+	}
+}
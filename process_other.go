@@ -0,0 +1,17 @@
+//go:build !nogui && !unix
+
+package main
+
+import (
+	"os"
+)
+
+// на Windows FindProcess открывает процесс и возвращает ошибку, если его уже нет
+func process_alive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,14 @@
+//go:build !nogui && unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// процесс с таким PID еще работает; сигнал 0 ничего не посылает, только проверяет
+func process_alive(pid int) bool {
+	var err = syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// цветовая тема: цвет живых клеток и фона
+type theme struct {
+	alive      color.RGBA
+	background color.RGBA
+}
+
+var themes = map[string]theme{
+	"light":   {color.RGBA{75, 139, 190, 255}, color.RGBA{255, 232, 115, 255}},
+	"classic": {color.RGBA{95, 95, 95, 255}, color.RGBA{233, 233, 233, 255}},
+	"dark":    {color.RGBA{230, 230, 230, 255}, color.RGBA{30, 30, 30, 255}},
+	// черно-белая, такие картинки читаются обратно как шаблоны
+	"mono": {color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}},
+}
+
+func theme_names() []string {
+	var names = []string{}
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// индексы цветов в палитре картинки
+const (
+	palette_background = 0
+	palette_alive      = 1
+	palette_grid       = 2
+)
+
+// линии сетки рисуем цветом посередине между фоном и клетками
+func (t theme) palette() color.Palette {
+	var grid = color.RGBA{
+		uint8((int(t.alive.R) + int(t.background.R)) / 2),
+		uint8((int(t.alive.G) + int(t.background.G)) / 2),
+		uint8((int(t.alive.B) + int(t.background.B)) / 2),
+		255,
+	}
+	return color.Palette{t.background, t.alive, grid}
+}
+
+// рисуем клетки из прямоугольника crop, каждая клетка занимает cell на cell пикселей,
+// сетка занимает верхний и левый ряд пикселей каждой клетки
+func render_cells(cells []POS, crop bounding_box, cell int, t theme, grid bool) *image.Paletted {
+	var img = image.NewPaletted(image.Rect(0, 0, crop.size_x()*cell, crop.size_y()*cell), t.palette())
+	if grid && cell > 1 {
+		for px := 0; px < img.Rect.Dx(); px++ {
+			for py := 0; py < img.Rect.Dy(); py++ {
+				if px%cell == 0 || py%cell == 0 {
+					img.SetColorIndex(px, py, palette_grid)
+				}
+			}
+		}
+	}
+	for _, c := range cells {
+		if c.x < crop.min_x || c.x > crop.max_x || c.y < crop.min_y || c.y > crop.max_y {
+			continue
+		}
+		var x0, y0 = (c.x - crop.min_x) * cell, (c.y - crop.min_y) * cell
+		for x := 0; x < cell; x++ {
+			for y := 0; y < cell; y++ {
+				if grid && cell > 1 && (x == 0 || y == 0) {
+					continue
+				}
+				img.SetColorIndex(x0+x, y0+y, palette_alive)
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// поиск не уложился в отведенное время
+var errSatTimeout = errors.New("time limit exceeded")
+
+const (
+	// как часто проверяем время, в решениях
+	sat_deadline_check = 1024
+	// перезапуск через столько противоречий, умноженных на число из последовательности Луби
+	sat_restart_base = 100
+	// насколько быстро забывается активность переменных
+	sat_activity_decay = 0.95
+)
+
+// решатель задачи выполнимости в КНФ с обучением дизъюнктов (CDCL): распространение по двум наблюдаемым
+// литералам, разбор противоречия до первой точки доминирования, возврат сразу на нужный уровень,
+// выбор самой активной переменной и перезапуски. Литералы как в DIMACS: v - переменная v истинна,
+// -v - ложна, переменные с 1
+type sat_solver struct {
+	nvars   int
+	clauses [][]int
+	// дизъюнкты, которые наблюдают литерал, индекс - lit_index(литерал)
+	watches [][]int
+	// по переменным: значение (0 - не задана, 1 - истина, -1 - ложь), уровень решения,
+	// дизъюнкт, из которого значение выведено (-1 - решение), активность и последнее значение
+	values   []int8
+	levels   []int
+	reasons  []int
+	activity []float64
+	phases   []int8
+	bump     float64
+	// назначенные литералы по порядку, где начинается каждый уровень и докуда уже распространили
+	trail     []int
+	trail_lim []int
+	head      int
+	// пустой дизъюнкт: задача невыполнима
+	empty    bool
+	deadline time.Time
+}
+
+func new_sat_solver(nvars int) *sat_solver {
+	var s = &sat_solver{
+		nvars:    nvars,
+		watches:  make([][]int, 2*nvars+2),
+		values:   make([]int8, nvars+1),
+		levels:   make([]int, nvars+1),
+		reasons:  make([]int, nvars+1),
+		activity: make([]float64, nvars+1),
+		phases:   make([]int8, nvars+1),
+		bump:     1,
+	}
+	// сначала пробуем ложь - для предшественников это значит меньше живых клеток
+	for v := range s.phases {
+		s.phases[v] = -1
+	}
+	return s
+}
+
+// новая переменная, например вспомогательная для счетчика
+func (s *sat_solver) new_var() int {
+	s.nvars++
+	s.watches = append(s.watches, nil, nil)
+	s.values = append(s.values, 0)
+	s.levels = append(s.levels, 0)
+	s.reasons = append(s.reasons, -1)
+	s.activity = append(s.activity, 0)
+	s.phases = append(s.phases, -1)
+	return s.nvars
+}
+
+// унарный счетчик (totalizer): out[k-1] истинна тогда и только тогда, когда истинны хотя бы k входов
+func (s *sat_solver) unary_count(inputs []int) []int {
+	if len(inputs) <= 1 {
+		return inputs
+	}
+	var left = s.unary_count(inputs[:len(inputs)/2])
+	var right = s.unary_count(inputs[len(inputs)/2:])
+	var out = make([]int, len(left)+len(right))
+	for i := range out {
+		out[i] = s.new_var()
+	}
+	for i := 0; i <= len(left); i++ {
+		for j := 0; j <= len(right); j++ {
+			// слева хотя бы i и справа хотя бы j - всего хотя бы i+j
+			if i+j > 0 {
+				var clause = []int{out[i+j-1]}
+				if i > 0 {
+					clause = append(clause, -left[i-1])
+				}
+				if j > 0 {
+					clause = append(clause, -right[j-1])
+				}
+				s.add_clause(clause)
+			}
+			// слева не больше i и справа не больше j - всего не больше i+j
+			if i+j < len(out) {
+				var clause = []int{-out[i+j]}
+				if i < len(left) {
+					clause = append(clause, left[i])
+				}
+				if j < len(right) {
+					clause = append(clause, right[j])
+				}
+				s.add_clause(clause)
+			}
+		}
+	}
+	return out
+}
+
+func lit_index(lit int) int {
+	if lit > 0 {
+		return 2 * lit
+	}
+	return -2*lit + 1
+}
+
+func abs_lit(lit int) int {
+	if lit < 0 {
+		return -lit
+	}
+	return lit
+}
+
+// значение литерала: 1 - истинен, -1 - ложен, 0 - переменная не задана
+func (s *sat_solver) value(lit int) int8 {
+	var v = s.values[abs_lit(lit)]
+	if lit < 0 {
+		return -v
+	}
+	return v
+}
+
+func (s *sat_solver) decision_level() int {
+	return len(s.trail_lim)
+}
+
+// добавляем дизъюнкт; решатель возвращается на нулевой уровень, литералы, ложные на нем, выбрасываем
+func (s *sat_solver) add_clause(clause []int) {
+	s.backtrack(0)
+	var kept = []int{}
+	for _, lit := range clause {
+		switch s.value(lit) {
+		case 1:
+			return
+		case 0:
+			kept = append(kept, lit)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		s.empty = true
+	case 1:
+		s.assign(kept[0], -1)
+	default:
+		s.watch(kept)
+	}
+}
+
+func (s *sat_solver) watch(clause []int) int {
+	var idx = len(s.clauses)
+	s.clauses = append(s.clauses, clause)
+	s.watches[lit_index(clause[0])] = append(s.watches[lit_index(clause[0])], idx)
+	s.watches[lit_index(clause[1])] = append(s.watches[lit_index(clause[1])], idx)
+	return idx
+}
+
+func (s *sat_solver) assign(lit int, reason int) {
+	var v = abs_lit(lit)
+	if lit > 0 {
+		s.values[v] = 1
+	} else {
+		s.values[v] = -1
+	}
+	s.levels[v] = s.decision_level()
+	s.reasons[v] = reason
+	s.trail = append(s.trail, lit)
+}
+
+// распространяем назначенные литералы; возвращаем дизъюнкт, в котором все литералы ложны, или -1
+func (s *sat_solver) propagate() int {
+	for ; s.head < len(s.trail); s.head++ {
+		// литерал, обратный назначенному, стал ложным, смотрим дизъюнкты, которые его наблюдают
+		var falsified = -s.trail[s.head]
+		var watching = s.watches[lit_index(falsified)]
+		var kept = watching[:0]
+		var conflict = -1
+		for i, idx := range watching {
+			if conflict != -1 {
+				kept = append(kept, watching[i:]...)
+				break
+			}
+			// наблюдаемые литералы стоят первыми, ложный переносим на второе место
+			var clause = s.clauses[idx]
+			if clause[0] == falsified {
+				clause[0], clause[1] = clause[1], clause[0]
+			}
+			if s.value(clause[0]) == 1 {
+				kept = append(kept, idx)
+				continue
+			}
+			var moved = false
+			for j := 2; j < len(clause); j++ {
+				if s.value(clause[j]) != -1 {
+					clause[1], clause[j] = clause[j], clause[1]
+					s.watches[lit_index(clause[1])] = append(s.watches[lit_index(clause[1])], idx)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				continue
+			}
+			kept = append(kept, idx)
+			if s.value(clause[0]) == -1 {
+				conflict = idx
+			} else {
+				s.assign(clause[0], idx)
+			}
+		}
+		s.watches[lit_index(falsified)] = kept
+		if conflict != -1 {
+			s.head = len(s.trail)
+			return conflict
+		}
+	}
+	return -1
+}
+
+// отменяем все назначения выше уровня level, значения переменных запоминаем для следующих решений
+func (s *sat_solver) backtrack(level int) {
+	if s.decision_level() <= level {
+		return
+	}
+	var start = s.trail_lim[level]
+	for _, lit := range s.trail[start:] {
+		var v = abs_lit(lit)
+		s.phases[v] = s.values[v]
+		s.values[v] = 0
+		s.reasons[v] = -1
+	}
+	s.trail = s.trail[:start]
+	s.trail_lim = s.trail_lim[:level]
+	s.head = start
+}
+
+func (s *sat_solver) bump_activity(v int) {
+	s.activity[v] += s.bump
+	if s.activity[v] > 1e100 {
+		for i := range s.activity {
+			s.activity[i] *= 1e-100
+		}
+		s.bump *= 1e-100
+	}
+}
+
+// разбираем противоречие до первой точки доминирования: получаем дизъюнкт, в котором на текущем уровне
+// остается один литерал, и уровень, на который можно сразу вернуться
+func (s *sat_solver) analyze(conflict int) ([]int, int) {
+	var learnt = []int{0}
+	var seen = map[int]bool{}
+	var counter = 0
+	var lit = 0
+	var idx = len(s.trail) - 1
+	var clause = s.clauses[conflict]
+	for {
+		for _, q := range clause {
+			var v = abs_lit(q)
+			// выведенный литерал стоит в своем дизъюнкте первым, его пропускаем
+			if q == lit || seen[v] || s.levels[v] == 0 {
+				continue
+			}
+			seen[v] = true
+			s.bump_activity(v)
+			if s.levels[v] == s.decision_level() {
+				counter++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+		for !seen[abs_lit(s.trail[idx])] {
+			idx--
+		}
+		lit = s.trail[idx]
+		idx--
+		seen[abs_lit(lit)] = false
+		counter--
+		if counter == 0 {
+			break
+		}
+		clause = s.clauses[s.reasons[abs_lit(lit)]]
+	}
+	learnt[0] = -lit
+
+	// второй наблюдаемый литерал - с самого высокого из оставшихся уровней, туда и возвращаемся
+	var level = 0
+	for i := 1; i < len(learnt); i++ {
+		if s.levels[abs_lit(learnt[i])] > level {
+			level = s.levels[abs_lit(learnt[i])]
+			learnt[1], learnt[i] = learnt[i], learnt[1]
+		}
+	}
+	s.bump /= sat_activity_decay
+	return learnt, level
+}
+
+// самая активная переменная без значения, 0 - все заданы
+func (s *sat_solver) pick() int {
+	var best = 0
+	for v := 1; v <= s.nvars; v++ {
+		if s.values[v] == 0 && (best == 0 || s.activity[v] > s.activity[best]) {
+			best = v
+		}
+	}
+	return best
+}
+
+// последовательность Луби 1, 1, 2, 1, 1, 2, 4, ... для длины перезапусков
+func luby(i int) int {
+	var size, seq = 1, 0
+	for size < i+1 {
+		seq++
+		size = 2*size + 1
+	}
+	for size-1 != i {
+		size = (size - 1) / 2
+		seq--
+		i = i % size
+	}
+	return 1 << seq
+}
+
+// ищем выполняющий набор; после успеха можно добавить дизъюнкты и искать снова
+func (s *sat_solver) solve() (bool, error) {
+	s.backtrack(0)
+	if s.empty || s.propagate() != -1 {
+		s.empty = true
+		return false, nil
+	}
+
+	var restarts, conflicts, decisions = 0, 0, 0
+	for {
+		if conflict := s.propagate(); conflict != -1 {
+			if s.decision_level() == 0 {
+				s.empty = true
+				return false, nil
+			}
+			conflicts++
+			var learnt, level = s.analyze(conflict)
+			s.backtrack(level)
+			if len(learnt) == 1 {
+				s.assign(learnt[0], -1)
+			} else {
+				s.assign(learnt[0], s.watch(learnt))
+			}
+			continue
+		}
+
+		if conflicts >= sat_restart_base*luby(restarts) {
+			conflicts = 0
+			restarts++
+			s.backtrack(0)
+		}
+		decisions++
+		if decisions%sat_deadline_check == 0 && !s.deadline.IsZero() && time.Now().After(s.deadline) {
+			s.backtrack(0)
+			return false, errSatTimeout
+		}
+		var v = s.pick()
+		if v == 0 {
+			return true, nil
+		}
+		s.trail_lim = append(s.trail_lim, len(s.trail))
+		s.assign(v*int(s.phases[v]), -1)
+	}
+}
+
+// найденный набор: значение каждой переменной
+func (s *sat_solver) model() []bool {
+	var model = make([]bool, s.nvars+1)
+	for v := 1; v <= s.nvars; v++ {
+		model[v] = s.values[v] == 1
+	}
+	return model
+}
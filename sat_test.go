@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// выполняет ли набор все дизъюнкты
+func satisfies(model []bool, clauses [][]int) bool {
+	for _, clause := range clauses {
+		var ok = false
+		for _, lit := range clause {
+			ok = ok || (lit > 0 && model[lit]) || (lit < 0 && !model[-lit])
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// решаем формулу; если выполнима, проверяем найденный набор
+func solve_formula(t *testing.T, nvars int, clauses [][]int) bool {
+	t.Helper()
+	var s = new_sat_solver(nvars)
+	for _, clause := range clauses {
+		s.add_clause(clause)
+	}
+	found, err := s.solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found && !satisfies(s.model(), clauses) {
+		t.Fatalf("model %v does not satisfy %v", s.model(), clauses)
+	}
+	return found
+}
+
+// p голубей в p-1 клетках: переменная (i, h) - голубь i сидит в клетке h
+func pigeonhole(p int) (int, [][]int) {
+	var v = func(i, h int) int { return 1 + i*(p-1) + h }
+	var clauses = [][]int{}
+	for i := 0; i < p; i++ {
+		var somewhere = []int{}
+		for h := 0; h < p-1; h++ {
+			somewhere = append(somewhere, v(i, h))
+		}
+		clauses = append(clauses, somewhere)
+	}
+	for h := 0; h < p-1; h++ {
+		for i := 0; i < p; i++ {
+			for j := i + 1; j < p; j++ {
+				clauses = append(clauses, []int{-v(i, h), -v(j, h)})
+			}
+		}
+	}
+	return p * (p - 1), clauses
+}
+
+func TestSatFormulas(t *testing.T) {
+	var php_vars, php = pigeonhole(6)
+	var tests = []struct {
+		name    string
+		nvars   int
+		clauses [][]int
+		want    bool
+	}{
+		{"empty", 3, nil, true},
+		{"unit chain", 3, [][]int{{1}, {-1, 2}, {-2, 3}}, true},
+		{"contradiction", 1, [][]int{{1}, {-1}}, false},
+		{"all four of two vars", 2, [][]int{{1, 2}, {1, -2}, {-1, 2}, {-1, -2}}, false},
+		{"xor chain", 4, [][]int{{1, 2}, {-1, -2}, {2, 3}, {-2, -3}, {3, 4}, {-3, -4}, {1, -4}}, true},
+		{"odd xor cycle", 3, [][]int{{1, 2}, {-1, -2}, {2, 3}, {-2, -3}, {3, 1}, {-3, -1}}, false},
+		{"6 pigeons in 5 holes", php_vars, php, false},
+	}
+	for _, test := range tests {
+		if got := solve_formula(t, test.nvars, test.clauses); got != test.want {
+			t.Errorf("%s: satisfiable %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// случайные 3-КНФ около порога выполнимости сверяем с полным перебором
+func TestSatRandomAgainstBruteForce(t *testing.T) {
+	const nvars, nclauses = 14, 60
+	var rng = rand.New(rand.NewSource(1))
+	var satisfiable = 0
+	for round := 0; round < 200; round++ {
+		var clauses = make([][]int, nclauses)
+		for i := range clauses {
+			for len(clauses[i]) < 3 {
+				var lit = 1 + rng.Intn(nvars)
+				if rng.Intn(2) == 0 {
+					lit = -lit
+				}
+				clauses[i] = append(clauses[i], lit)
+			}
+		}
+		var want = false
+		var model = make([]bool, nvars+1)
+		for bits := 0; bits < 1<<nvars && !want; bits++ {
+			for v := 1; v <= nvars; v++ {
+				model[v] = bits>>(v-1)&1 == 1
+			}
+			want = satisfies(model, clauses)
+		}
+		if want {
+			satisfiable++
+		}
+		if got := solve_formula(t, nvars, clauses); got != want {
+			t.Fatalf("round %d: satisfiable %v, brute force says %v: %v", round, got, want, clauses)
+		}
+	}
+	// иначе проверка ничего не значит
+	if satisfiable == 0 || satisfiable == 200 {
+		t.Fatalf("%d of 200 formulas satisfiable, expected a mix", satisfiable)
+	}
+}
+
+// после найденного набора дизъюнкты можно добавлять: перебираем все наборы (x1 или x2) и x3
+func TestSatEnumerateModels(t *testing.T) {
+	var s = new_sat_solver(3)
+	s.add_clause([]int{1, 2})
+	s.add_clause([]int{3})
+	var models = 0
+	for {
+		found, err := s.solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			break
+		}
+		models++
+		var block = []int{}
+		for v, value := range s.model()[1:] {
+			if value {
+				block = append(block, -(v + 1))
+			} else {
+				block = append(block, v+1)
+			}
+		}
+		s.add_clause(block)
+	}
+	if models != 3 {
+		t.Errorf("%d models, want 3", models)
+	}
+}
+
+// счетчик: при k истинных входах истинны ровно первые k выходов
+func TestSatUnaryCount(t *testing.T) {
+	for n := 1; n <= 6; n++ {
+		for k := 0; k <= n; k++ {
+			var s = new_sat_solver(n)
+			var inputs = []int{}
+			for v := 1; v <= n; v++ {
+				inputs = append(inputs, v)
+				if v <= k {
+					s.add_clause([]int{v})
+				} else {
+					s.add_clause([]int{-v})
+				}
+			}
+			var out = s.unary_count(inputs)
+			found, err := s.solve()
+			if err != nil || !found {
+				t.Fatalf("%d of %d inputs: found %v, %v", k, n, found, err)
+			}
+			var model = s.model()
+			for i, v := range out {
+				if model[v] != (i < k) {
+					t.Errorf("%d of %d inputs: output %d is %v", k, n, i+1, model[v])
+				}
+			}
+		}
+	}
+}
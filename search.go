@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// база результатов поиска по умолчанию, одна строка JSON на суп
+	search_db_file = "search.jsonl"
+	// поле считаем устоявшимся, если популяция повторяется с периодом до search_max_period
+	// на протяжении последних search_stable_window поколений; улетающие планеры стираются и в популяцию не входят
+	search_max_period    = 60
+	search_stable_window = 400
+	// сколько семян каждого объекта показываем в отчете
+	search_report_seeds = 3
+	// сколько строк в отчете о самых редких находках
+	search_report_lines = 30
+)
+
+// результат одного супа в базе; по seed и настройкам суп можно повторить
+type search_record struct {
+	Seed        int64          `json:"seed"`
+	Size        int            `json:"size"`
+	Density     float64        `json:"density"`
+	Symmetry    string         `json:"symmetry"`
+	Rule        string         `json:"rule"`
+	Generations int            `json:"generations"`
+	Stable      bool           `json:"stable"`
+	Objects     map[string]int `json:"objects"` // apgcode или "unknown" -> сколько раз
+}
+
+// популяция последних поколений повторяется с каким-то периодом
+func population_periodic(history []int) bool {
+	if len(history) < search_stable_window+search_max_period {
+		return false
+	}
+	var n = len(history) - 1
+	for p := 1; p <= search_max_period; p++ {
+		var periodic = true
+		for i := 0; i < search_stable_window && periodic; i++ {
+			periodic = history[n-i] == history[n-i-p]
+		}
+		if periodic {
+			return true
+		}
+	}
+	return false
+}
+
+// считаем один суп до устоявшегося состояния и переписываем объекты.
+// Улетевшие корабли стираем, как в run_methuselah, чтобы поле не росло, но учитываем в переписи
+func run_soup(settings soup_settings, max_gens int) search_record {
+	var soup = generate_soup(settings, rand.New(rand.NewSource(settings.seed)))
+	var n = settings.size
+	var u = new_universe(pattern_from_field(soup, bounding_box{0, 0, n - 1, n - 1, false}))
+
+	var record = search_record{
+		Seed:     settings.seed,
+		Size:     settings.size,
+		Density:  settings.density,
+		Symmetry: symmetry_names[settings.symmetry],
+		Rule:     active_rule.String(),
+		Objects:  map[string]int{},
+	}
+	var escapes = new_escape_detector(true)
+	var history = []int{u.population()}
+	for u.generation < max_gens {
+		u.step()
+		if u.generation%escape_check_interval == 0 {
+			var escaped = u.observe_escapes(escapes)
+			for _, find := range escaped {
+				record.Objects[canonical_object(find.cells).apgcode]++
+			}
+			if len(escaped) > 0 {
+				u.crop()
+			}
+		}
+		history = append(history, u.population())
+		// проверять каждое поколение незачем
+		if u.generation%50 == 0 && population_periodic(history) {
+			record.Stable = true
+			break
+		}
+	}
+	record.Generations = u.generation
+
+	for _, entry := range take_census(u.cells(), census_distance) {
+		var code = entry.object.apgcode
+		if code == "" {
+			code = "unknown"
+		}
+		record.Objects[code] += entry.count
+	}
+	return record
+}
+
+// семена супов с теми же настройками, что уже есть в базе
+func searched_seeds(records []search_record, settings soup_settings) map[int64]bool {
+	var seeds = map[int64]bool{}
+	for _, record := range records {
+		if record.Size == settings.size && record.Density == settings.density &&
+			record.Symmetry == symmetry_names[settings.symmetry] && record.Rule == active_rule.String() {
+			seeds[record.Seed] = true
+		}
+	}
+	return seeds
+}
+
+// считаем супы seed, seed+1, ... в нескольких горутинах и дописываем результаты в базу;
+// семена из done пропускаем, чтобы повторный запуск не дописывал те же супы
+func run_search(base soup_settings, soups int, workers int, max_gens int, done map[int64]bool, db io.Writer) error {
+	var seeds = make(chan int64)
+	var records = make(chan search_record)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seed := range seeds {
+				var settings = base
+				settings.seed = seed
+				records <- run_soup(settings, max_gens)
+			}
+		}()
+	}
+	go func() {
+		for seed, sent := base.seed, 0; sent < soups; seed++ {
+			if !done[seed] {
+				seeds <- seed
+				sent++
+			}
+		}
+		close(seeds)
+		wg.Wait()
+		close(records)
+	}()
+
+	// пишем по мере готовности, порядок строк не важен
+	var encoder = json.NewEncoder(db)
+	var err error
+	for record := range records {
+		if err == nil {
+			err = encoder.Encode(record)
+		}
+	}
+	return err
+}
+
+// читаем базу, битые строки (например, недописанные при остановке) пропускаем
+func read_search_db(r io.Reader) ([]search_record, int, error) {
+	var records = []search_record{}
+	var skipped = 0
+	var scanner = bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record search_record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, skipped, scanner.Err()
+}
+
+// сколько раз встретился объект и в каких супах
+type search_find struct {
+	code  string
+	count int
+	soups []search_record
+}
+
+// отчет: самые редкие объекты первыми, у каждого - семена, по которым суп можно повторить
+func write_search_report(w io.Writer, records []search_record) {
+	var finds = map[string]*search_find{}
+	var unstable = 0
+	for _, record := range records {
+		if !record.Stable {
+			unstable++
+		}
+		for code, count := range record.Objects {
+			var find, ok = finds[code]
+			if !ok {
+				find = &search_find{code: code}
+				finds[code] = find
+			}
+			find.count += count
+			if len(find.soups) < search_report_seeds {
+				find.soups = append(find.soups, record)
+			}
+		}
+	}
+
+	var sorted = []*search_find{}
+	for code, find := range finds {
+		if code != "unknown" {
+			sorted = append(sorted, find)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count < sorted[j].count
+		}
+		return sorted[i].code < sorted[j].code
+	})
+
+	fmt.Fprintf(w, "%d soups, %d did not settle, %d distinct objects\n", len(records), unstable, len(sorted))
+	if find, ok := finds["unknown"]; ok {
+		fmt.Fprintf(w, "%d objects that did not stabilize in isolation\n", find.count)
+	}
+	fmt.Fprintln(w, "rarest objects:")
+	for i, find := range sorted {
+		if i == search_report_lines {
+			break
+		}
+		var name = ""
+		if p, err := parse_apgcode(find.code); err == nil {
+			if object := classify_object(p.cells); object.name != "" {
+				name = " (" + object.name + ")"
+			}
+		}
+		var seeds = []string{}
+		for _, soup := range find.soups {
+			seeds = append(seeds, fmt.Sprint(soup.Seed))
+		}
+		fmt.Fprintf(w, "%8d  %s%s  seeds %s\n", find.count, find.code, name, strings.Join(seeds, ", "))
+		// первый суп показываем командой, которая откроет его в окне
+		var soup = find.soups[0]
+		fmt.Fprintf(w, "          life -seed %d -soup -soup-size %d -density %g -symmetry %s -rule %s\n",
+			soup.Seed, soup.Size, soup.Density, soup.Symmetry, soup.Rule)
+	}
+}
+
+// life search: много случайных супов подряд, перепись каждого и отчет о редких находках
+func search_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life search", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var soups = flags.Int("soups", 100, "number of soups")
+	var workers = flags.Int("workers", runtime.NumCPU(), "soups computed in parallel")
+	var max_gens = flags.Int("gens", 10000, "give up on a soup after this many generations")
+	var db_file = flags.String("db", search_db_file, "JSON lines file the results are appended to")
+	var report_only = flags.Bool("report", false, "only print the report for the existing database")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	if *soups < 1 || *workers < 1 || *max_gens < 1 {
+		fmt.Fprintln(stderr, "life search: soups, workers and gens must be positive")
+		return exit_usage
+	}
+	if options.pattern_file != "" {
+		fmt.Fprintln(stderr, "life search: searches random soups, -pattern is not supported")
+		return exit_usage
+	}
+	if _, err := options.apply(); err != nil {
+		fmt.Fprintln(stderr, "life search:", err)
+		return exit_usage
+	}
+
+	if !*report_only {
+		var done = map[int64]bool{}
+		if existing, err := os.Open(*db_file); err == nil {
+			records, _, err := read_search_db(existing)
+			existing.Close()
+			if err != nil {
+				fmt.Fprintln(stderr, "life search:", err)
+				return exit_error
+			}
+			done = searched_seeds(records, options.soup)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(stderr, "life search:", err)
+			return exit_error
+		}
+		if len(done) > 0 {
+			fmt.Fprintf(stderr, "life search: %d soups with these settings are already in %s, their seeds are skipped\n", len(done), *db_file)
+		}
+
+		db, err := os.OpenFile(*db_file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintln(stderr, "life search:", err)
+			return exit_error
+		}
+		err = run_search(options.soup, *soups, *workers, *max_gens, done, db)
+		if close_err := db.Close(); err == nil {
+			err = close_err
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "life search:", err)
+			return exit_error
+		}
+	}
+
+	db, err := os.Open(*db_file)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(stderr, "life search: no results yet in", *db_file)
+		return exit_error
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "life search:", err)
+		return exit_error
+	}
+	defer db.Close()
+	records, skipped, err := read_search_db(db)
+	if err != nil {
+		fmt.Fprintln(stderr, "life search:", err)
+		return exit_error
+	}
+	if skipped > 0 {
+		fmt.Fprintf(stderr, "life search: skipped %d broken lines in %s\n", skipped, *db_file)
+	}
+	write_search_report(stdout, records)
+	return exit_ok
+}
@@ -0,0 +1,68 @@
+//go:build !nogui
+
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+var selection_color = color.RGBA{40, 120, 220, 200}
+
+// выделение храним без origin: когда поле растет влево или вверх, индексы field сдвигаются, а оно нет
+func (g *MyGame) set_selection(a POS, b POS) {
+	g.selection = bounding_box{min(a.x, b.x) - g.origin.x, min(a.y, b.y) - g.origin.y,
+		max(a.x, b.x) - g.origin.x, max(a.y, b.y) - g.origin.y, false}
+}
+
+func (g *MyGame) clear_selection() {
+	g.selection = bounding_box{empty: true}
+}
+
+// выделение в координатах field, обрезанное по границам поля
+func (g *MyGame) selection_box() bounding_box {
+	if g.selection.empty {
+		return g.selection
+	}
+	var box = bounding_box{max(g.selection.min_x+g.origin.x, 0), max(g.selection.min_y+g.origin.y, 0),
+		min(g.selection.max_x+g.origin.x, g.height-1), min(g.selection.max_y+g.origin.y, g.width-1), false}
+	if box.min_x > box.max_x || box.min_y > box.max_y {
+		return bounding_box{empty: true}
+	}
+	return box
+}
+
+// живые клетки внутри выделения в координатах field
+func (g *MyGame) selection_cells() []POS {
+	var box = g.selection_box()
+	var cells = []POS{}
+	if box.empty {
+		return cells
+	}
+	for x := box.min_x; x <= box.max_x; x++ {
+		for y := box.min_y; y <= box.max_y; y++ {
+			if g.field[x][y] == 1 {
+				cells = append(cells, POS{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// рамка выделения поверх клеток
+func (g *MyGame) drawSelection(screen *ebiten.Image) {
+	var box = g.selection_box()
+	if box.empty {
+		return
+	}
+	var x0 = float64((box.min_x - g.x_offset) * scale)
+	var y0 = float64((box.min_y - g.y_offset) * scale)
+	var w = float64(box.size_x() * scale)
+	var h = float64(box.size_y() * scale)
+	ebitenutil.DrawRect(screen, x0, y0, w, 1, selection_color)
+	ebitenutil.DrawRect(screen, x0, y0+h-1, w, 1, selection_color)
+	ebitenutil.DrawRect(screen, x0, y0, 1, h, selection_color)
+	ebitenutil.DrawRect(screen, x0+w-1, y0, 1, h, selection_color)
+}
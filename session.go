@@ -0,0 +1,161 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"slices"
+)
+
+// файл сессии по Ctrl+S / Ctrl+O
+const session_file = "life.session"
+
+// имя текущей темы по цветам, если цвета ни с одной не совпали - тема по умолчанию
+func current_theme_name() string {
+	for _, name := range theme_names() {
+		if themes[name] == (theme{black, white}) {
+			return name
+		}
+	}
+	return default_config().Theme
+}
+
+// снимаем состояние игры, поле только копируем: перевести его в RLE можно и в фоне через set_board
+func (g *MyGame) capture_session() (session_data, [][]byte) {
+	var stamp = [][2]int{}
+	if g.is_figure_draw {
+		for _, pix := range g.pixels {
+			if pix.value == 1 {
+				stamp = append(stamp, [2]int{pix.x, pix.y})
+			}
+		}
+	}
+
+	return session_data{
+		Version:    session_version,
+		Generation: g.stats.generation,
+		Rule:       active_rule.String(),
+		Topology:   active_topology,
+		XOffset:    g.x_offset,
+		YOffset:    g.y_offset,
+		CellSize:   scale,
+		Speed:      g.speed_idx,
+		Paused:     g.is_pause,
+		Theme:      current_theme_name(),
+		Seed:       g.seed,
+		Soup: session_soup{
+			Size:      g.soup.size,
+			Density:   g.soup.density,
+			Symmetry:  symmetry_names[g.soup.symmetry],
+			Placement: placement_names[g.soup.placement],
+			Seed:      g.soup.seed,
+		},
+		Stamp:  stamp,
+		Origin: [2]int{g.origin.x, g.origin.y},
+	}, copy_field(g.field)
+}
+
+// восстанавливаем игру из сессии, сначала все проверяем, чтобы при ошибке не испортить текущее поле
+func (g *MyGame) restore_session(s session_data) error {
+	rule, err := parse_rule(s.Rule)
+	if err != nil {
+		return err
+	}
+	topology, err := parse_topology(s.Topology)
+	if err != nil {
+		return err
+	}
+	t, ok := themes[s.Theme]
+	if !ok {
+		return fmt.Errorf("session: unknown theme %q", s.Theme)
+	}
+	symmetry, err := parse_symmetry(s.Soup.Symmetry)
+	if err != nil {
+		return err
+	}
+	var placement = slices.Index(placement_names, s.Soup.Placement)
+	if placement == -1 {
+		return fmt.Errorf("session: unknown soup placement %q", s.Soup.Placement)
+	}
+	if s.CellSize < 1 || s.CellSize > 32 {
+		return fmt.Errorf("session: cell size %d: expected 1..32", s.CellSize)
+	}
+	if n := max_soup_size(screenWidth, screenHeight, s.CellSize); s.Soup.Size < 1 || s.Soup.Size > n {
+		return fmt.Errorf("session: soup size %d: expected 1..%d", s.Soup.Size, n)
+	}
+	if s.XOffset < 0 || s.YOffset < 0 || s.Generation < 0 {
+		return fmt.Errorf("session: negative offset or generation")
+	}
+	board, err := s.board()
+	if err != nil {
+		return err
+	}
+
+	active_rule = rule
+	active_topology = topology
+	black, white = t.alive, t.background
+	set_layout(screenWidth, screenHeight, s.CellSize)
+
+	g.field = board.to_field()
+	g.height = board.size_x
+	g.width = board.size_y
+	g.x_offset = s.XOffset
+	g.y_offset = s.YOffset
+	g.origin = POS{s.Origin[0], s.Origin[1]}
+	g.ensure_field_size(g.x_offset+gameHeight, g.y_offset+gameWidth)
+
+	g.set_speed_level(s.Speed)
+	g.is_pause = s.Paused
+	g.seed = s.Seed
+	g.rng = rand.New(rand.NewSource(s.Seed))
+	g.soup = soup_settings{s.Soup.Size, s.Soup.Density, symmetry, placement, s.Soup.Seed}
+
+	g.clear_selection()
+	g.pixels = nil
+	for _, p := range s.Stamp {
+		g.pixels = append(g.pixels, PIXEL{p[0], p[1], 1})
+	}
+	g.is_figure_draw = len(g.pixels) > 0
+
+	g.stats = life_stats{generation: s.Generation}
+	g.series = nil
+	g.snapshots = nil
+	g.rate_gens = 0
+	g.reset_period()
+	g.escapes.reset()
+	return nil
+}
+
+// пишем во временный файл и переименовываем, чтобы не оставить наполовину записанную сессию
+func (g *MyGame) save_session(filename string) error {
+	var s, field = g.capture_session()
+	if err := s.set_board(field); err != nil {
+		return err
+	}
+	if err := save_session_file(filename, s); err != nil {
+		return err
+	}
+	log.Printf("session saved to %s", filename)
+	return nil
+}
+
+func (g *MyGame) load_session(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	s, err := read_session(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if err := g.restore_session(s); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	log.Printf("session loaded from %s", filename)
+	return nil
+}
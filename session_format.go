@@ -0,0 +1,120 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// версия формата, увеличиваем при любом несовместимом изменении session_data
+const session_version = 1
+
+// настройки супа в сессии, симметрию и размещение храним по именам
+type session_soup struct {
+	Size      int     `json:"size"`
+	Density   float64 `json:"density"`
+	Symmetry  string  `json:"symmetry"`
+	Placement string  `json:"placement"`
+	Seed      int64   `json:"seed"`
+}
+
+// все, что нужно, чтобы продолжить с того же места; ключи JSON не переименовываем,
+// иначе перестанут читаться старые сохранения
+type session_data struct {
+	Version    int    `json:"version"`
+	Generation int    `json:"generation"`
+	Rule       string `json:"rule"`
+	Topology   string `json:"topology"`
+	// поле целиком в RLE, вместе с пустыми краями
+	Board    string       `json:"board"`
+	XOffset  int          `json:"x_offset"`
+	YOffset  int          `json:"y_offset"`
+	CellSize int          `json:"cell_size"`
+	Speed    int          `json:"speed"`
+	Paused   bool         `json:"paused"`
+	Theme    string       `json:"theme"`
+	Seed     int64        `json:"seed"`
+	Soup     session_soup `json:"soup"`
+	// фигура с кнопки, которую пользователь еще не поставил, пары x, y
+	Stamp [][2]int `json:"stamp"`
+	// сколько строк и колонок добавлено в начало поля, в старых сессиях нет - тогда 0
+	Origin [2]int `json:"origin"`
+}
+
+// приводим старые версии формата к текущей, файлы из новых версий не читаем
+func migrate_session(s *session_data) error {
+	if s.Version < 1 {
+		return fmt.Errorf("session: missing version")
+	}
+	if s.Version > session_version {
+		return fmt.Errorf("session: version %d is newer than supported %d", s.Version, session_version)
+	}
+	return nil
+}
+
+// пишем сессию как сжатый JSON
+func write_session(w io.Writer, s session_data) error {
+	var zw = gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func read_session(r io.Reader) (session_data, error) {
+	var s = session_data{}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return s, fmt.Errorf("session: %w", err)
+	}
+	defer zr.Close()
+	if err := json.NewDecoder(zr).Decode(&s); err != nil {
+		return s, fmt.Errorf("session: %w", err)
+	}
+	return s, migrate_session(&s)
+}
+
+// записываем поле в сессию как RLE
+func (s *session_data) set_board(field [][]byte) error {
+	var board strings.Builder
+	var p = pattern_from_field(field, bounding_box{0, 0, len(field) - 1, len(field[0]) - 1, false})
+	rule, err := parse_rule(s.Rule)
+	if err != nil {
+		return err
+	}
+	if err := write_rle(&board, p, rule); err != nil {
+		return err
+	}
+	s.Board = board.String()
+	return nil
+}
+
+// читаем поле из сессии
+func (s session_data) board() (pattern, error) {
+	board, err := parse_rle(strings.NewReader(s.Board))
+	if err != nil {
+		return board, fmt.Errorf("session: %w", err)
+	}
+	return board, nil
+}
+
+func save_session_file(filename string, s session_data) error {
+	var tmp = filename + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write_session(file, s); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
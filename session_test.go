@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// поле проходит через сессию без сдвига: пустые строки сверху и колонки слева сохраняются, origin тоже
+func TestSessionBoardRoundTrip(t *testing.T) {
+	var field = make([][]byte, 12)
+	for x := range field {
+		field[x] = make([]byte, 20)
+	}
+	for _, c := range []POS{{3, 5}, {3, 6}, {3, 7}, {7, 9}, {11, 19}} {
+		field[c.x][c.y] = 1
+	}
+	var s = session_data{Version: session_version, Rule: "B3/S23", Origin: [2]int{5, 7}}
+	if err := s.set_board(field); err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	if err := write_session(&file, s); err != nil {
+		t.Fatal(err)
+	}
+	back, err := read_session(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board, err := back.board()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored = board.to_field()
+	if !slices.EqualFunc(restored, field, slices.Equal[[]byte]) {
+		t.Errorf("board %q restores as %v", back.Board, field_cells(board.size_x, board.size_y, restored, POS{}))
+	}
+	if back.Origin != s.Origin {
+		t.Errorf("origin %v, want %v", back.Origin, s.Origin)
+	}
+}
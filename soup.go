@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// симметрии супа, как в apgsearch
+const (
+	symmetry_c1 = iota
+	symmetry_c2
+	symmetry_c4
+	symmetry_d2
+	symmetry_d4
+	symmetry_d8
+)
+
+var symmetry_names = []string{"C1", "C2", "C4", "D2", "D4", "D8"}
+
+func parse_symmetry(text string) (int, error) {
+	for i, name := range symmetry_names {
+		if strings.EqualFold(name, text) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("symmetry %q: expected one of %s", text, strings.Join(symmetry_names, ", "))
+}
+
+// где ставим суп
+const (
+	placement_center = iota
+	placement_cursor
+	placement_random
+)
+
+var placement_names = []string{"center", "cursor", "random"}
+
+const (
+	default_soup_size    = 16
+	default_soup_density = 0.5
+)
+
+// настройки генератора супа
+type soup_settings struct {
+	size      int
+	density   float64
+	symmetry  int
+	placement int
+	seed      int64
+}
+
+// первый суп берет зерно игры, дальше зерно меняется командами
+func default_soup_settings(seed int64) soup_settings {
+	return soup_settings{
+		size:      default_soup_size,
+		density:   default_soup_density,
+		symmetry:  symmetry_c1,
+		placement: placement_center,
+		seed:      seed,
+	}
+}
+
+func (s soup_settings) String() string {
+	return fmt.Sprintf("%dx%d %s %.0f%% seed %d (%s)", s.size, s.size, symmetry_names[s.symmetry], s.density*100, s.seed, placement_names[s.placement])
+}
+
+// преобразования квадрата n на n, из которых собираются группы симметрий
+type square_transform func(p POS, n int) POS
+
+func transform_identity(p POS, n int) POS     { return p }
+func transform_rotate90(p POS, n int) POS     { return POS{p.y, n - 1 - p.x} }
+func transform_rotate180(p POS, n int) POS    { return POS{n - 1 - p.x, n - 1 - p.y} }
+func transform_rotate270(p POS, n int) POS    { return POS{n - 1 - p.y, p.x} }
+func transform_mirror_x(p POS, n int) POS     { return POS{n - 1 - p.x, p.y} }
+func transform_mirror_y(p POS, n int) POS     { return POS{p.x, n - 1 - p.y} }
+func transform_diagonal(p POS, n int) POS     { return POS{p.y, p.x} }
+func transform_antidiagonal(p POS, n int) POS { return POS{n - 1 - p.y, n - 1 - p.x} }
+
+// группа преобразований для каждой симметрии
+func symmetry_group(symmetry int) []square_transform {
+	switch symmetry {
+	case symmetry_c2:
+		return []square_transform{transform_identity, transform_rotate180}
+	case symmetry_c4:
+		return []square_transform{transform_identity, transform_rotate90, transform_rotate180, transform_rotate270}
+	case symmetry_d2:
+		return []square_transform{transform_identity, transform_mirror_x}
+	case symmetry_d4:
+		return []square_transform{transform_identity, transform_mirror_x, transform_mirror_y, transform_rotate180}
+	case symmetry_d8:
+		return []square_transform{transform_identity, transform_rotate90, transform_rotate180, transform_rotate270,
+			transform_mirror_x, transform_mirror_y, transform_diagonal, transform_antidiagonal}
+	}
+	return []square_transform{transform_identity}
+}
+
+// представитель орбиты клетки: наименьшая клетка, в которую она переходит
+func orbit_representative(p POS, n int, group []square_transform) POS {
+	var best = p
+	for _, transform := range group {
+		var q = transform(p, n)
+		if q.x < best.x || (q.x == best.x && q.y < best.y) {
+			best = q
+		}
+	}
+	return best
+}
+
+// генерируем суп: случайный квадрат, в котором клетки одной орбиты совпадают
+func generate_soup(settings soup_settings, rng *rand.Rand) [][]byte {
+	var n = settings.size
+	var base = generate_field(n, n, settings.density, rng)
+	var group = symmetry_group(settings.symmetry)
+
+	var soup = make([][]byte, n)
+	for x := 0; x < n; x++ {
+		soup[x] = make([]byte, n)
+		for y := 0; y < n; y++ {
+			var rep = orbit_representative(POS{x, y}, n, group)
+			soup[x][y] = base[rep.x][rep.y]
+		}
+	}
+	return soup
+}
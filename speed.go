@@ -0,0 +1,143 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// ограничения для команды "шаг на N поколений"
+	max_step_count = 4096
+
+	// гиперскорость увеличивает число поколений за кадр, пока успевает в бюджет кадра
+	max_hyper_gens   = 1 << 16
+	hyper_grow_time  = 8 * time.Millisecond
+	hyper_limit_time = 14 * time.Millisecond
+)
+
+// скорость симуляции: новое поколение раз в frames_per_gen кадров,
+// и за один раз считаем gens_per_frame поколений
+type speed_level struct {
+	frames_per_gen int
+	gens_per_frame int
+}
+
+// от 1 поколения в секунду до 64 поколений за кадр
+var speed_levels = []speed_level{
+	{60, 1}, {30, 1}, {20, 1}, {10, 1}, {5, 1}, {2, 1}, {1, 1},
+	{1, 2}, {1, 4}, {1, 8}, {1, 16}, {1, 32}, {1, 64},
+}
+
+// уровни, которые включаются клавишами 1, 2, 3
+const (
+	speed_level_slow   = 2
+	speed_level_medium = 3
+	speed_level_fast   = 6
+)
+
+// включаем уровень скорости из таблицы
+func (g *MyGame) set_speed_level(idx int) {
+	g.speed_idx = max(0, min(idx, len(speed_levels)-1))
+	g.max_counter = speed_levels[g.speed_idx].frames_per_gen
+	g.gens_per_frame = speed_levels[g.speed_idx].gens_per_frame
+	g.hyperspeed = false
+}
+
+// скорость в поколениях в секунду, которую мы пытаемся держать
+func (g *MyGame) target_rate() float64 {
+	return float64(ebiten.TPS()) / float64(max(g.max_counter, 1)) * float64(g.gens_per_frame)
+}
+
+// переходим к следующему поколению
+func (g *MyGame) step_generation() {
+	var extender, changes = next_generation(g.height, g.width, g.field)
+	g.field = extender.field
+	g.height = extender.height
+	g.width = extender.width
+	g.x_offset += extender.x_offset
+	g.y_offset += extender.y_offset
+	g.origin.x += extender.x_offset
+	g.origin.y += extender.y_offset
+
+	g.count_generation(changes)
+	g.detect_period()
+	g.detect_escapes()
+}
+
+// считаем сразу n поколений
+func (g *MyGame) step_generations(n int) {
+	for i := 0; i < n; i++ {
+		g.step_generation()
+	}
+}
+
+// считаем поколения за кадр, в режиме гиперскорости подстраиваем их число
+func (g *MyGame) run_frame() {
+	var start = time.Now()
+	g.step_generations(g.gens_per_frame)
+	if !g.hyperspeed {
+		return
+	}
+
+	var spent = time.Since(start)
+	if spent < hyper_grow_time && g.gens_per_frame < max_hyper_gens {
+		g.gens_per_frame *= 2
+	} else if spent > hyper_limit_time && g.gens_per_frame > 1 {
+		g.gens_per_frame /= 2
+	}
+}
+
+// клавиши управления скоростью и пошаговым режимом
+func (g *MyGame) speedKeyEvent() {
+	// быстрее/медленнее
+	if g.is_action_just_pressed(action_faster) {
+		g.set_speed_level(g.speed_idx + 1)
+	}
+	if g.is_action_just_pressed(action_slower) {
+		g.set_speed_level(g.speed_idx - 1)
+	}
+
+	// размер шага для команды "шаг на N поколений"
+	if g.is_action_just_pressed(action_step_count_up) {
+		g.step_count = min(g.step_count*2, max_step_count)
+	}
+	if g.is_action_just_pressed(action_step_count_down) {
+		g.step_count = max(g.step_count/2, 1)
+	}
+
+	// один шаг или шаг на step_count поколений, работает и на паузе
+	if g.is_action_just_pressed(action_step) {
+		g.step_generation()
+	}
+	if g.is_action_just_pressed(action_step_many) {
+		g.step_generations(g.step_count)
+	}
+
+	// гиперскорость; при выключении возвращаемся к выбранному уровню скорости
+	if g.is_action_just_pressed(action_hyperspeed) {
+		g.is_pause = false
+		if g.hyperspeed {
+			g.set_speed_level(g.speed_idx)
+		} else {
+			g.hyperspeed = true
+			g.max_counter = 1
+			g.gens_per_frame = 1
+		}
+	}
+}
+
+// строка для HUD с текущими настройками скорости
+func (g *MyGame) speed_description() string {
+	var mode = "normal"
+	if g.hyperspeed {
+		mode = "hyper"
+	}
+	if g.is_pause {
+		mode = "paused"
+	}
+	return fmt.Sprintf("Target: %.1f gen/s (%d gen/frame, %s), step %d", g.target_rate(), g.gens_per_frame, mode, g.step_count)
+}
@@ -0,0 +1,346 @@
+//go:build !nogui
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// инструменты рисования
+const (
+	tool_brush = iota
+	tool_line
+	tool_rect
+	tool_rect_filled
+	tool_ellipse
+	tool_ellipse_filled
+	tool_fill
+	tool_select
+)
+
+var tool_names = []string{"Brush", "Line", "Rect", "FRect", "Ellipse", "FEllipse", "Fill", "Select"}
+
+// что кисть делает с клеткой
+const (
+	mode_draw = iota
+	mode_erase
+	mode_toggle
+)
+
+var mode_names = []string{"Draw", "Erase", "Toggle"}
+
+const max_brush_size = 16
+
+var preview_color = color.RGBA{200, 50, 50, 160}
+
+// переводим координаты экрана в координаты поля
+func (g *MyGame) screen_to_field(x, y int) POS {
+	return POS{g.x_offset + x/scale, g.y_offset + y/scale}
+}
+
+// курсор над игровой зоной
+func in_game_area(x, y int) bool {
+	return x >= 0 && y >= 0 && x < gameHeight*scale && y < gameWidth*scale
+}
+
+// меняем клетку в зависимости от режима, за пределами поля ничего не делаем
+func (g *MyGame) apply_cell(p POS, mode int) {
+	if p.x < 0 || p.y < 0 || p.x >= g.height || p.y >= g.width {
+		return
+	}
+	g.reset_period()
+	switch mode {
+	case mode_draw:
+		g.field[p.x][p.y] = 1
+	case mode_erase:
+		g.field[p.x][p.y] = 0
+	case mode_toggle:
+		g.field[p.x][p.y] = 1 - g.field[p.x][p.y]
+	}
+}
+
+// квадрат brush_size на brush_size с центром в p
+func brush_cells(p POS, size int) []POS {
+	var cells = []POS{}
+	var start = (size - 1) / 2
+	for dx := 0; dx < size; dx++ {
+		for dy := 0; dy < size; dy++ {
+			cells = append(cells, POS{p.x - start + dx, p.y - start + dy})
+		}
+	}
+	return cells
+}
+
+// отрезок по алгоритму Брезенхэма
+func line_cells(from POS, to POS) []POS {
+	var cells = []POS{}
+	var dx = abs(to.x - from.x)
+	var dy = -abs(to.y - from.y)
+	var sx = sign(to.x - from.x)
+	var sy = sign(to.y - from.y)
+	var err = dx + dy
+	var p = from
+	for {
+		cells = append(cells, p)
+		if p == to {
+			return cells
+		}
+		var e2 = 2 * err
+		if e2 >= dy {
+			err += dy
+			p.x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			p.y += sy
+		}
+	}
+}
+
+// прямоугольник по двум углам, залитый или только контур
+func rect_cells(a POS, b POS, filled bool) []POS {
+	var cells = []POS{}
+	var x0, x1 = min(a.x, b.x), max(a.x, b.x)
+	var y0, y1 = min(a.y, b.y), max(a.y, b.y)
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			if filled || x == x0 || x == x1 || y == y0 || y == y1 {
+				cells = append(cells, POS{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// эллипс, вписанный в прямоугольник по двум углам
+func ellipse_cells(a POS, b POS, filled bool) []POS {
+	var x0, x1 = min(a.x, b.x), max(a.x, b.x)
+	var y0, y1 = min(a.y, b.y), max(a.y, b.y)
+	var cx = float64(x0+x1) / 2
+	var cy = float64(y0+y1) / 2
+	var rx = float64(x1-x0)/2 + 0.5
+	var ry = float64(y1-y0)/2 + 0.5
+
+	var inside = func(x, y int) bool {
+		var nx = (float64(x) - cx) / rx
+		var ny = (float64(y) - cy) / ry
+		return nx*nx+ny*ny <= 1
+	}
+
+	var cells = []POS{}
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			if !inside(x, y) {
+				continue
+			}
+			// для контура берем клетки, у которых есть сосед снаружи
+			if filled || !inside(x-1, y) || !inside(x+1, y) || !inside(x, y-1) || !inside(x, y+1) {
+				cells = append(cells, POS{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// заливка связной области одинаковых клеток, не выходя за видимую часть поля
+func (g *MyGame) flood_fill(start POS, mode int) {
+	var min_x, max_x = g.x_offset, min(g.x_offset+gameHeight, g.height) - 1
+	var min_y, max_y = g.y_offset, min(g.y_offset+gameWidth, g.width) - 1
+	if start.x < min_x || start.x > max_x || start.y < min_y || start.y > max_y {
+		return
+	}
+
+	var target = g.field[start.x][start.y]
+	var visited = map[POS]bool{start: true}
+	var stack = []POS{start}
+	for len(stack) > 0 {
+		var p = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		g.apply_cell(p, mode)
+
+		for _, next := range []POS{{p.x + 1, p.y}, {p.x - 1, p.y}, {p.x, p.y + 1}, {p.x, p.y - 1}} {
+			if next.x < min_x || next.x > max_x || next.y < min_y || next.y > max_y {
+				continue
+			}
+			if visited[next] || g.field[next.x][next.y] != target {
+				continue
+			}
+			visited[next] = true
+			stack = append(stack, next)
+		}
+	}
+}
+
+// клетки фигуры, которую сейчас тянут мышью
+func (g *MyGame) shape_cells(from POS, to POS) []POS {
+	switch g.tool {
+	case tool_line:
+		var cells = []POS{}
+		for _, p := range line_cells(from, to) {
+			cells = append(cells, brush_cells(p, g.brush_size)...)
+		}
+		return cells
+	case tool_rect, tool_rect_filled:
+		return rect_cells(from, to, g.tool == tool_rect_filled)
+	case tool_ellipse, tool_ellipse_filled:
+		return ellipse_cells(from, to, g.tool == tool_ellipse_filled)
+	case tool_select:
+		// рамка только для показа, на поле ее не переносим
+		return rect_cells(from, to, false)
+	}
+	return nil
+}
+
+// мазок кистью от прошлой позиции курсора до текущей, чтобы не было пропусков
+func (g *MyGame) stroke(from POS, to POS, mode int) {
+	for _, p := range line_cells(from, to) {
+		for _, cell := range brush_cells(p, g.brush_size) {
+			// в режиме переключения каждую клетку меняем один раз за мазок
+			if g.stroke_cells[cell] {
+				continue
+			}
+			g.stroke_cells[cell] = true
+			g.apply_cell(cell, mode)
+		}
+	}
+}
+
+// обрабатываем мышь в игровой зоне: левая кнопка рисует текущим режимом, правая стирает
+func (g *MyGame) handle_tool_mouse(mx, my int) {
+	var p = g.screen_to_field(mx, my)
+
+	if !g.drag_active {
+		var button = ebiten.MouseButtonLeft
+		var mode = g.brush_mode
+		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+			button = ebiten.MouseButtonRight
+			mode = mode_erase
+		} else if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			return
+		}
+		if !in_game_area(mx, my) {
+			return
+		}
+
+		if g.tool == tool_fill {
+			g.flood_fill(p, mode)
+			return
+		}
+		g.drag_active = true
+		g.drag_button = button
+		g.drag_mode = mode
+		g.drag_start = p
+		g.drag_last = p
+		g.stroke_cells = map[POS]bool{}
+		if g.tool == tool_brush {
+			g.stroke(p, p, mode)
+		}
+		return
+	}
+
+	// кнопку отпустили: фигуру переносим на поле
+	if !ebiten.IsMouseButtonPressed(g.drag_button) {
+		g.drag_active = false
+		if g.tool == tool_select {
+			// щелчок без протягивания или правой кнопкой снимает выделение
+			if g.drag_start == g.drag_last || g.drag_button == ebiten.MouseButtonRight {
+				g.clear_selection()
+			} else {
+				g.set_selection(g.drag_start, g.drag_last)
+			}
+			return
+		}
+		for _, cell := range g.shape_cells(g.drag_start, g.drag_last) {
+			g.apply_cell(cell, g.drag_mode)
+		}
+		return
+	}
+
+	if in_game_area(mx, my) {
+		if g.tool == tool_brush {
+			g.stroke(g.drag_last, p, g.drag_mode)
+		}
+		g.drag_last = p
+	}
+}
+
+// клавиши выбора инструмента, режима и размера кисти
+func (g *MyGame) toolKeyEvent() {
+	var tools = []struct {
+		action string
+		tool   int
+	}{
+		{action_tool_brush, tool_brush},
+		{action_tool_line, tool_line},
+		{action_tool_rect, tool_rect},
+		{action_tool_rect_filled, tool_rect_filled},
+		{action_tool_ellipse, tool_ellipse},
+		{action_tool_ellipse_filled, tool_ellipse_filled},
+		{action_tool_fill, tool_fill},
+		{action_tool_select, tool_select},
+	}
+	for _, t := range tools {
+		if g.is_action_just_pressed(t.action) {
+			g.tool = t.tool
+			g.drag_active = false
+		}
+	}
+
+	if g.is_action_just_pressed(action_mode_draw) {
+		g.brush_mode = mode_draw
+	}
+	if g.is_action_just_pressed(action_mode_erase) {
+		g.brush_mode = mode_erase
+	}
+	if g.is_action_just_pressed(action_mode_toggle) {
+		g.brush_mode = mode_toggle
+	}
+	if g.is_action_just_pressed(action_brush_bigger) {
+		g.brush_size = min(g.brush_size+1, max_brush_size)
+	}
+	if g.is_action_just_pressed(action_brush_smaller) {
+		g.brush_size = max(g.brush_size-1, 1)
+	}
+}
+
+// палитра инструментов внизу игровой зоны, активный инструмент в скобках
+func (g *MyGame) drawPalette(screen *ebiten.Image) {
+	var names = []string{}
+	for i, name := range tool_names {
+		if i == g.tool {
+			name = "[" + name + "]"
+		}
+		names = append(names, name)
+	}
+	var line = fmt.Sprintf("%s | %s | Size %d", strings.Join(names, " "), mode_names[g.brush_mode], g.brush_size)
+	ebitenutil.DebugPrintAt(screen, line, 0, screenHeight-hud_line_height)
+
+	// пока тянем фигуру, показываем ее поверх поля
+	if !g.drag_active || g.tool == tool_brush {
+		return
+	}
+	for _, cell := range g.shape_cells(g.drag_start, g.drag_last) {
+		var sx = float64((cell.x - g.x_offset) * scale)
+		var sy = float64((cell.y - g.y_offset) * scale)
+		if !in_game_area(int(sx), int(sy)) {
+			continue
+		}
+		ebitenutil.DrawRect(screen, sx, sy, float64(scale), float64(scale), preview_color)
+	}
+}
+
+func sign(x int) int {
+	if x < 0 {
+		return -1
+	}
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// скорости терминального режима в поколениях в секунду
+var tui_speeds = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600, 1200}
+
+const (
+	tui_default_speed = 3
+	tui_max_zoom      = 64
+	// на сколько символов сдвигаем вид одной стрелкой
+	tui_pan_chars = 8
+)
+
+// состояние терминального режима
+type tui_state struct {
+	u *universe
+	// абсолютные координаты клетки в левом верхнем углу экрана
+	camera  POS
+	zoom    int // сколько клеток на одну точку по каждой оси
+	braille bool
+	paused  bool
+	speed   int
+	// накопленные дробные поколения между кадрами
+	pending float64
+	quit    bool
+
+	cols int
+	rows int
+}
+
+// сколько точек в одном символе: шрифт Брайля 2x4, полублоки 1x2
+func (t *tui_state) dots_per_char() (int, int) {
+	if t.braille {
+		return 2, 4
+	}
+	return 1, 2
+}
+
+// размер видимой области в клетках, последняя строка экрана под статус
+func (t *tui_state) view_size() (int, int) {
+	var dx, dy = t.dots_per_char()
+	return t.cols * dx * t.zoom, max(t.rows-1, 1) * dy * t.zoom
+}
+
+// ставим камеру так, чтобы живые клетки были в центре экрана
+func (t *tui_state) center() {
+	var box = t.u.box()
+	var view_x, view_y = t.view_size()
+	if box.empty {
+		t.camera = POS{-view_x / 2, -view_y / 2}
+		return
+	}
+	var center_x = (box.min_x+box.max_x)/2 - t.u.origin.x
+	var center_y = (box.min_y+box.max_y)/2 - t.u.origin.y
+	t.camera = POS{center_x - view_x/2, center_y - view_y/2}
+}
+
+// обрабатываем нажатые клавиши, стрелки приходят как ESC [ A..D
+func (t *tui_state) handle_input(data []byte) {
+	var _, dy = t.dots_per_char()
+	var pan = tui_pan_chars * dy * t.zoom
+	for i := 0; i < len(data); i++ {
+		var c = data[i]
+		if c == 0x1b && i+2 < len(data) && data[i+1] == '[' {
+			switch data[i+2] {
+			case 'A':
+				t.camera.y -= pan
+			case 'B':
+				t.camera.y += pan
+			case 'C':
+				t.camera.x += pan
+			case 'D':
+				t.camera.x -= pan
+			}
+			i += 2
+			continue
+		}
+		switch c {
+		case 'q', 3: // q или Ctrl+C
+			t.quit = true
+		case ' ':
+			t.paused = !t.paused
+		case 'n':
+			t.u.step()
+		case '+', '=':
+			t.speed = min(t.speed+1, len(tui_speeds)-1)
+		case '-':
+			t.speed = max(t.speed-1, 0)
+		case 'z':
+			t.zoom = max(t.zoom/2, 1)
+		case 'x':
+			t.zoom = min(t.zoom*2, tui_max_zoom)
+		case 'b':
+			t.braille = !t.braille
+		case 'c':
+			t.center()
+		case 'w', 'k':
+			t.camera.y -= pan
+		case 's', 'j':
+			t.camera.y += pan
+		case 'd', 'l':
+			t.camera.x += pan
+		case 'a', 'h':
+			t.camera.x -= pan
+		}
+	}
+}
+
+// считаем поколения, которые набежали за время кадра
+func (t *tui_state) advance(elapsed time.Duration) {
+	if t.paused {
+		t.pending = 0
+		return
+	}
+	t.pending += tui_speeds[t.speed] * elapsed.Seconds()
+	for t.pending >= 1 {
+		t.u.step()
+		t.pending--
+	}
+}
+
+// биты точки внутри символа Брайля (U+2800)
+var braille_bits = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// рисуем кадр целиком: поле, затем строка статуса
+func (t *tui_state) render(w io.Writer) error {
+	var dx, dy = t.dots_per_char()
+	var lines = max(t.rows-1, 1)
+
+	// переносим живые клетки в точки экрана
+	var dots = make([][]bool, t.cols*dx)
+	for i := range dots {
+		dots[i] = make([]bool, lines*dy)
+	}
+	for x := 0; x < t.u.height; x++ {
+		for y := 0; y < t.u.width; y++ {
+			if t.u.field[x][y] == 0 {
+				continue
+			}
+			var px = x - t.u.origin.x - t.camera.x
+			var py = y - t.u.origin.y - t.camera.y
+			if px < 0 || py < 0 {
+				continue
+			}
+			px /= t.zoom
+			py /= t.zoom
+			if px < len(dots) && py < lines*dy {
+				dots[px][py] = true
+			}
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("\x1b[H")
+	for row := 0; row < lines; row++ {
+		for col := 0; col < t.cols; col++ {
+			out.WriteRune(t.char_at(dots, col*dx, row*dy))
+		}
+		out.WriteString("\x1b[K\r\n")
+	}
+
+	var state = fmt.Sprintf("%.0f gen/s", tui_speeds[t.speed])
+	if t.paused {
+		state = "paused"
+	}
+	var status = fmt.Sprintf("gen %d  pop %d  %s  zoom 1:%d  [space] pause [n] step [+/-] speed [arrows] pan [z/x] zoom [b] braille [c] center [q] quit",
+		t.u.generation, t.u.population(), state, t.zoom)
+	if len(status) > t.cols {
+		status = status[:t.cols]
+	}
+	out.WriteString("\x1b[7m" + status + "\x1b[0m\x1b[K")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// символ для блока точек с левым верхним углом (x, y)
+func (t *tui_state) char_at(dots [][]bool, x int, y int) rune {
+	if t.braille {
+		var r = rune(0x2800)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 4; j++ {
+				if dots[x+i][y+j] {
+					r |= braille_bits[i][j]
+				}
+			}
+		}
+		if r == 0x2800 {
+			return ' '
+		}
+		return r
+	}
+	switch {
+	case dots[x][y] && dots[x][y+1]:
+		return '█'
+	case dots[x][y]:
+		return '▀'
+	case dots[x][y+1]:
+		return '▄'
+	}
+	return ' '
+}
+
+// life tui: то же поле, что и в окне, но в терминале
+func tui_command(args []string, stdout io.Writer, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("life tui", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options = start_options{}
+	options.register(flags)
+	var fps = flags.Int("fps", 30, "frames per second")
+	var half_blocks = flags.Bool("half-blocks", false, "draw with half blocks instead of braille")
+	if err := flags.Parse(args); err != nil {
+		return exit_usage
+	}
+	if *fps < 1 {
+		fmt.Fprintln(stderr, "life tui: fps must be positive")
+		return exit_usage
+	}
+
+	start, err := options.apply()
+	if err != nil {
+		fmt.Fprintln(stderr, "life tui:", err)
+		return exit_usage
+	}
+
+	var fd = int(os.Stdin.Fd())
+	restore, err := make_raw_terminal(fd)
+	if err != nil {
+		fmt.Fprintln(stderr, "life tui: stdin is not a terminal:", err)
+		return exit_error
+	}
+	defer restore()
+
+	// альтернативный экран и скрытый курсор, на выходе возвращаем как было
+	io.WriteString(stdout, "\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer io.WriteString(stdout, "\x1b[?25h\x1b[?1049l")
+
+	var input = make(chan []byte)
+	go func() {
+		var buf = make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(input)
+				return
+			}
+			input <- append([]byte{}, buf[:n]...)
+		}
+	}()
+
+	var t = &tui_state{u: new_universe(start), zoom: 1, braille: !*half_blocks, paused: true, speed: tui_default_speed}
+	t.cols, t.rows, _ = terminal_size(fd)
+	t.center()
+
+	var ticker = time.NewTicker(time.Second / time.Duration(*fps))
+	defer ticker.Stop()
+	var last = time.Now()
+	for !t.quit {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				return exit_ok
+			}
+			t.handle_input(data)
+		case now := <-ticker.C:
+			t.advance(now.Sub(last))
+			last = now
+		}
+
+		// размер терминала проверяем каждый кадр, при изменении перерисовываем все
+		cols, rows, err := terminal_size(fd)
+		if err == nil && (cols != t.cols || rows != t.rows) {
+			t.cols, t.rows = cols, rows
+			io.WriteString(stdout, "\x1b[2J")
+		}
+		if err := t.render(stdout); err != nil {
+			return exit_error
+		}
+	}
+	return exit_ok
+}
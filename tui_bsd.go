@@ -0,0 +1,13 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// на macOS и BSD те же настройки терминала читаются и пишутся другими запросами ioctl
+const (
+	ioctl_get_termios = unix.TIOCGETA
+	ioctl_set_termios = unix.TIOCSETA
+)
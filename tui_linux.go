@@ -0,0 +1,11 @@
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// запросы ioctl для настроек терминала на Linux
+const (
+	ioctl_get_termios = unix.TCGETS
+	ioctl_set_termios = unix.TCSETS
+)
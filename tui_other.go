@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"errors"
+)
+
+var errNoTerminal = errors.New("terminal mode is only supported on Linux, macOS and BSD")
+
+func make_raw_terminal(fd int) (func(), error) {
+	return nil, errNoTerminal
+}
+
+func terminal_size(fd int) (int, int, error) {
+	return 80, 24, errNoTerminal
+}
@@ -0,0 +1,151 @@
+package main
+
+// сколько пустых клеток держим вокруг живых, чтобы next_generation не обрезал фигуры у края
+const universe_margin = 2
+
+// поле для расчетов без окна: то же, что в MyGame, но без камеры и интерфейса
+type universe struct {
+	height int
+	width  int
+	field  [][]byte
+	// клетка field[x][y] имеет абсолютные координаты (x - origin.x, y - origin.y),
+	// origin растет, когда поле расширяется влево или вверх
+	origin     POS
+	generation int
+	// на плоскости поле растет вслед за клетками, тор не растет; задается при создании,
+	// чтобы считать объект на плоскости, не трогая active_topology, пока поле - тор
+	topology string
+}
+
+// кладем шаблон так, что его клетка (0, 0) имеет абсолютные координаты (0, 0)
+func new_universe(p pattern) *universe {
+	var u = &universe{
+		height:   p.size_x + 2*universe_margin,
+		width:    p.size_y + 2*universe_margin,
+		origin:   POS{universe_margin, universe_margin},
+		topology: active_topology,
+	}
+	u.field = make([][]byte, u.height)
+	for x := range u.field {
+		u.field[x] = make([]byte, u.width)
+	}
+	for _, cell := range p.cells {
+		u.field[cell.x+universe_margin][cell.y+universe_margin] = 1
+	}
+	return u
+}
+
+// на плоскости отдельный универсум
+func new_plane_universe(p pattern) *universe {
+	var u = new_universe(p)
+	u.topology = topology_plane
+	return u
+}
+
+// добавляем пустые строки и колонки, если живые клетки подошли к краю.
+// Отступ в universe_margin пустых клеток нужен и когда active_topology - тор: тогда next_generation
+// поле не расширяет, а соседи за краем берутся с пустого противоположного края
+func (u *universe) pad() {
+	if u.topology != topology_plane {
+		return
+	}
+	var box = find_bounding_box(u.height, u.width, u.field)
+	if box.empty {
+		return
+	}
+
+	// сколько не хватает до нужного отступа с каждой стороны, считаем до изменения поля
+	var top = universe_margin - box.min_x
+	var bottom = universe_margin - (u.height - 1 - box.max_x)
+	var left = universe_margin - box.min_y
+	var right = universe_margin - (u.width - 1 - box.max_y)
+
+	for i := 0; i < top; i++ {
+		u.field = prepend_row(u.width, u.field)
+		u.height++
+		u.origin.x++
+	}
+	for i := 0; i < bottom; i++ {
+		u.field = append(u.field, make([]byte, u.width))
+		u.height++
+	}
+	for i := 0; i < left; i++ {
+		u.field = prepend_column(u.height, u.field)
+		u.width++
+		u.origin.y++
+	}
+	for i := 0; i < right; i++ {
+		u.field = append_column(0, u.height, u.field, make([][]byte, 0, u.height))
+		u.width++
+	}
+}
+
+// обрезаем поле до живых клеток с отступом, например после того как стерли улетевшие корабли;
+// абсолютные координаты клеток не меняются
+func (u *universe) crop() {
+	var box = u.box()
+	if box.empty {
+		return
+	}
+	var field = make([][]byte, box.size_x()+2*universe_margin)
+	for x := range field {
+		field[x] = make([]byte, box.size_y()+2*universe_margin)
+	}
+	for x := box.min_x; x <= box.max_x; x++ {
+		copy(field[x-box.min_x+universe_margin][universe_margin:], u.field[x][box.min_y:box.max_y+1])
+	}
+	u.field = field
+	u.height = len(field)
+	u.width = len(field[0])
+	u.origin.x += universe_margin - box.min_x
+	u.origin.y += universe_margin - box.min_y
+}
+
+// переход к следующему поколению через next_generation
+func (u *universe) step() life_changes {
+	u.pad()
+	var extender, changes = next_generation(u.height, u.width, u.field)
+	u.field = extender.field
+	u.height = extender.height
+	u.width = extender.width
+	u.origin.x += extender.x_offset
+	u.origin.y += extender.y_offset
+	u.generation++
+	return changes
+}
+
+func (u *universe) population() int {
+	return count_population(u.height, u.width, u.field)
+}
+
+func (u *universe) box() bounding_box {
+	return find_bounding_box(u.height, u.width, u.field)
+}
+
+// живые клетки, обрезанные по прямоугольнику
+func (u *universe) pattern() pattern {
+	return pattern_from_field(u.field, u.box())
+}
+
+// ищем повтор текущего поколения
+func (u *universe) observe(d *period_detector) period_result {
+	return d.observe(u.height, u.width, u.field, u.origin, u.generation)
+}
+
+// ищем улетающие корабли, при d.remove они стираются с поля
+func (u *universe) observe_escapes(d *escape_detector) []escape_find {
+	return d.observe(u.height, u.width, u.field, u.origin, u.generation)
+}
+
+// живые клетки в абсолютных координатах
+func (u *universe) cells() []POS {
+	var cells = []POS{}
+	for x := 0; x < u.height; x++ {
+		for y := 0; y < u.width; y++ {
+			if u.field[x][y] == 1 {
+				cells = append(cells, POS{x - u.origin.x, y - u.origin.y})
+			}
+		}
+	}
+	return cells
+}